@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is the request context key for the App Context of the request
+type contextKey struct{}
+
+// MountMethods are the methods a net/http handler is registered for by Group.Mount
+var MountMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// MountParam is the name of the URL parameter holding the path below the mount point of a handler
+const MountParam = "path"
+
+// ContextFromRequest returns the App Context of a request served by the net/http handlers and
+// middlewares adapted with HTTPHandler and HTTPMiddleware
+func ContextFromRequest(r *http.Request) (*Context, bool) {
+	ctx, ok := r.Context().Value(contextKey{}).(*Context)
+	return ctx, ok
+}
+
+// withContext returns the Context request, carrying the Context in its context.Context
+func withContext(ctx *Context) *http.Request {
+	if c, ok := ContextFromRequest(ctx.Request); ok && c == ctx {
+		return ctx.Request
+	}
+	return ctx.Request.WithContext(context.WithValue(ctx.Context(), contextKey{}, ctx))
+}
+
+// HTTPHandler adapts a net/http handler to be used as a Handler.
+// The App Context is available to the handler through ContextFromRequest.
+func HTTPHandler(h http.Handler) Handler {
+	return HandlerFunc(func(ctx *Context) error {
+		h.ServeHTTP(ctx.ResponseWriter, withContext(ctx))
+		return nil
+	})
+}
+
+// HTTPMiddleware adapts a net/http middleware to be used as a Middleware, so it can be part of a
+// MiddlewareChain. The ResponseWriter and Request the middleware passes down are set in the Context
+// while serving the next handlers, whose errors are returned as usual.
+func HTTPMiddleware(mw func(http.Handler) http.Handler) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx *Context) error {
+			var err error
+			w, r := ctx.ResponseWriter, ctx.Request
+			inner := http.HandlerFunc(func(iw http.ResponseWriter, ir *http.Request) {
+				ctx.ResponseWriter, ctx.Request = iw, ir
+				err = next.Serve(ctx)
+			})
+			mw(inner).ServeHTTP(w, withContext(ctx))
+			ctx.ResponseWriter, ctx.Request = w, r
+			return err
+		})
+	}
+}
+
+// StdHandler adapts a Handler to be used as a net/http handler. A new Context is created for
+// every request, unless it is already served by the App, in which case its Context is used.
+func (a *App) StdHandler(h Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := ContextFromRequest(r)
+		if !ok {
+			Dispatch(a.NewContextHTTP(w, r), h)
+			return
+		}
+		pw, pr := ctx.ResponseWriter, ctx.Request
+		ctx.ResponseWriter, ctx.Request = w, r
+		if err := h.Serve(ctx); err != nil {
+			a.HandleError(ctx, err)
+		}
+		ctx.ResponseWriter, ctx.Request = pw, pr
+	})
+}
+
+// StdMiddleware adapts a Middleware to be used as a net/http middleware
+func (a *App) StdMiddleware(mw Middleware) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return a.StdHandler(mw(HTTPHandler(next)))
+	}
+}
+
+// Mount registers a net/http handler for every method in MountMethods, serving the given path
+// prefix of the group and everything below it. The remaining path is available in the MountParam
+// URL parameter; the request path is not modified, so use http.StripPrefix if the handler expects it.
+func (g *Group) Mount(prefix string, h http.Handler) {
+	handler := HTTPHandler(h)
+	for _, method := range MountMethods {
+		g.Handle(method, joinPath(prefix, "*"+MountParam), handler)
+	}
+}
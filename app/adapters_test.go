@@ -0,0 +1,91 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/log"
+)
+
+// stdTracer is a net/http middleware recording its name and passing a context value down
+func stdTracer(name string, trace *[]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*trace = append(*trace, name)
+			w.Header().Set("X-"+name, "true")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey(name), name)))
+		})
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	var trace []string
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	errTest := &app.HTTPError{Status: http.StatusTeapot, Code: "teapot"}
+
+	h := chain.New(
+		tracer("app", &trace),
+		app.HTTPMiddleware(stdTracer("std", &trace)),
+	).Finally(app.HandlerFunc(func(ctx *app.Context) error {
+		trace = append(trace, "handler")
+		if ctx.Context().Value(ctxKey("std")) != "std" {
+			t.Error("expecting the request of the net/http middleware in the Context")
+		}
+		if c, ok := app.ContextFromRequest(ctx.Request); !ok || c != ctx {
+			t.Error("expecting the Context to be available from the request")
+		}
+		return errTest
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	ctx := a.NewContextHTTP(rec, req)
+	err := h.Serve(ctx)
+	if err != errTest {
+		t.Errorf("expecting error %v, got %v", errTest, err)
+	}
+	if ctx.Request != req {
+		t.Error("expecting the original request to be restored in the Context")
+	}
+	if expected := []string{"app", "std", "handler"}; !reflect.DeepEqual(expected, trace) {
+		t.Errorf("expecting trace %v, got %v", expected, trace)
+	}
+	if rec.Header().Get("X-std") != "true" {
+		t.Error("expecting the header set by the net/http middleware")
+	}
+}
+
+func TestStdHandler(t *testing.T) {
+	var trace []string
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace = append(trace, "std handler")
+		if _, ok := app.ContextFromRequest(r); !ok {
+			t.Error("expecting the Context to be available from the request")
+		}
+	})
+	h := stdTracer("std", &trace)(a.StdMiddleware(tracer("app", &trace))(final))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if expected := []string{"std", "app", "std handler"}; !reflect.DeepEqual(expected, trace) {
+		t.Errorf("expecting trace %v, got %v", expected, trace)
+	}
+
+	rec = httptest.NewRecorder()
+	a.StdHandler(app.HandlerFunc(func(*app.Context) error {
+		return errors.New("failed")
+	})).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expecting handler errors to be handled by the App, got status %d", rec.Code)
+	}
+}
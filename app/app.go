@@ -1,13 +1,16 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/syb-devs/goth/database"
-	"github.com/syb-devs/goth/encoding/json"
 	"github.com/syb-devs/goth/kv"
 	"github.com/syb-devs/goth/log"
 )
@@ -20,21 +23,39 @@ type App struct {
 		database.Connection
 		database.Repository
 		*database.ResourceMap
+		// NewRepository is used to bind a Repository to the per request
+		// copies of the Connection. If not set, the App Repository is shared.
+		NewRepository database.RepositoryFactory
 	}
 	Log     log.Logger
+	Config  *Config
 	muxer   Muxer
 	handler http.Handler
 	modules map[string]Module
+	order   []string
+	started []string
 	mws     map[string]MiddlewareChain
+
+	errHandler ErrorHandler
+	errMappers []ErrorMapper
+
+	routes      []*Route
+	namedRoutes map[string]*Route
+	hosts       []*VHost
+	dispatch    atomic.Value
 }
 
-// NewApp instances and returns an App with the given name
+// NewApp instances and returns an App with the given name. The modules registered
+// with RegisterModule are copied into the App registry as its default module set.
 func NewApp(name string) *App {
 	return &App{
-		name:    name,
-		modules: modules,
-		mws:     make(map[string]MiddlewareChain),
-		Log:     log.New(os.Stderr),
+		name:        name,
+		modules:     defaultModules(),
+		mws:         make(map[string]MiddlewareChain),
+		namedRoutes: make(map[string]*Route),
+		errMappers:  defaultErrorMappers(),
+		Log:         log.New(os.Stderr),
+		Config:      NewConfig(),
 	}
 }
 
@@ -43,6 +64,67 @@ func (a *App) Name() string {
 	return a.name
 }
 
+// Use registers the given modules in the App
+func (a *App) Use(mods ...Module) {
+	a.Lock()
+	defer a.Unlock()
+	for _, mod := range mods {
+		name := mod.Name()
+		if _, exists := a.modules[name]; exists {
+			panic(fmt.Sprintf("module %s already registered in app %s", name, a.name))
+		}
+		a.modules[name] = mod
+	}
+}
+
+// RemoveModule removes the modules with the given names from the App, so they are not bootstrapped.
+// It is useful for opting out of some of the default modules.
+func (a *App) RemoveModule(names ...string) {
+	a.Lock()
+	defer a.Unlock()
+	for _, name := range names {
+		delete(a.modules, name)
+	}
+}
+
+// Module returns the module registered in the App with the given name
+func (a *App) Module(name string) (Module, bool) {
+	a.Lock()
+	defer a.Unlock()
+	mod, ok := a.modules[name]
+	return mod, ok
+}
+
+// Modules returns the modules registered in the App. The bootstrapped ones come first,
+// in dependency order, followed by the rest sorted by name.
+func (a *App) Modules() []Module {
+	a.Lock()
+	defer a.Unlock()
+	mods := make([]Module, 0, len(a.modules))
+	seen := make(map[string]bool, len(a.order))
+	for _, name := range a.order {
+		if mod, ok := a.modules[name]; ok {
+			mods = append(mods, mod)
+			seen[name] = true
+		}
+	}
+	var rest []string
+	for name := range a.modules {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		mods = append(mods, a.modules[name])
+	}
+	return mods
+}
+
+// DefaultChain is the name of the MiddlewareChain wrapping the automatic 405 and OPTIONS answers
+// of the routes registered without a chain (i.e. with App.Handle), if any is added with it
+const DefaultChain = "main"
+
 // AddChain adds a MiddlewareChain to be used when registering handlers
 func (a *App) AddChain(chain MiddlewareChain, name string) {
 	a.Lock()
@@ -50,6 +132,18 @@ func (a *App) AddChain(chain MiddlewareChain, name string) {
 	a.mws[name] = chain
 }
 
+// Chain returns the MiddlewareChain registered with the given name, so it can be used
+// as the base of other chains, i.e.:
+//
+//	main, _ := a.Chain("main")
+//	a.AddChain(main.Append(authMiddleware), "auth")
+func (a *App) Chain(name string) (MiddlewareChain, bool) {
+	a.Lock()
+	defer a.Unlock()
+	chain, ok := a.mws[name]
+	return chain, ok
+}
+
 // WrapHandler wraps the given Handler with a MiddlewareChain
 func (a *App) WrapHandler(h Handler, chainName string) Handler {
 	chain, ok := a.mws[chainName]
@@ -64,10 +158,74 @@ func (a *App) WrapHandlerFunc(h HandlerFunc, chainName string) Handler {
 	return a.WrapHandler(h, chainName)
 }
 
-// Handle registers a handler for a given method / path combination
-func (a *App) Handle(method, path string, h Handler) {
+// Handle registers a handler for a given method / path combination, wrapped with the given middlewares.
+// The returned Route can be named for reverse routing.
+func (a *App) Handle(method, path string, h Handler, mws ...Middleware) *Route {
+	return a.handle(nil, method, path, "", h, mws)
+}
+
+// handle registers the handler in the Muxer of the given virtual host, or the App one if nil,
+// wrapped with the given middlewares and the MiddlewareChain with the given name, if any.
+// The constraints of the URL parameters in the path are checked right before running the handler,
+// once the Muxer has matched the route, so it panics if the route only differs from an already
+// registered one by its URL parameters.
+func (a *App) handle(host *VHost, method, path, chainName string, h Handler, mws []Middleware) *Route {
 	log.Debugf("registering route %s %s", method, path)
-	a.muxer.Handle(method, path, h)
+	original := path
+	path, constraints := parsePattern(path)
+	h = constrain(wrap(h, mws), constraints)
+	if chainName != "" {
+		h = a.WrapHandler(h, chainName)
+	}
+	muxer := a.muxer
+	r := &Route{Method: method, Pattern: path, Chain: chainName, app: a}
+	if host != nil {
+		muxer = host.muxer
+		r.Host = host.pattern
+	}
+	for _, c := range constraints {
+		if r.Params == nil {
+			r.Params = make(map[string]string)
+		}
+		r.Params[c.name] = c.expr
+	}
+	a.Lock()
+	conflict := a.paramConflict(r)
+	a.Unlock()
+	if conflict != nil {
+		panic(fmt.Sprintf("route %s %s conflicts with %s: routes can not differ only by their URL parameters, "+
+			"as the constraints are checked after the route is matched", method, original, conflict.Pattern))
+	}
+	muxer.Handle(method, path, h)
+
+	a.Lock()
+	first := !a.hasPattern(r.Host, path)
+	a.routes = append(a.routes, r)
+	a.Unlock()
+
+	// The 405 and OPTIONS answers go through the same middlewares as the route, or the
+	// DefaultChain if the route has no chain
+	if fm, ok := muxer.(FallbackMuxer); ok && first {
+		fallback := wrap(a.allowHandler(r.Host, path), mws)
+		if chainName != "" {
+			fallback = a.WrapHandler(fallback, chainName)
+		} else {
+			fallback = a.withDefaultChain(fallback)
+		}
+		fm.HandleFallback(path, fallback)
+	}
+	return r
+}
+
+// withDefaultChain wraps the Handler with the DefaultChain, looked up on each request,
+// as it can be added after the routes
+func (a *App) withDefaultChain(h Handler) Handler {
+	return HandlerFunc(func(ctx *Context) error {
+		if chain, ok := a.Chain(DefaultChain); ok {
+			return chain.Finally(h).Serve(ctx)
+		}
+		return h.Serve(ctx)
+	})
 }
 
 // SetMuxer sets a Muxer for the App object
@@ -75,52 +233,145 @@ func (a *App) SetMuxer(m Muxer) {
 	a.Lock()
 	defer a.Unlock()
 	a.muxer = m
+	a.updateDispatch()
 }
 
-// SetHandler sets a Handler for the App object
+// SetHandler sets the Handler serving the requests that do not match any virtual host.
+// If not set, the App Muxer is used.
 func (a *App) SetHandler(h http.Handler) {
 	a.Lock()
 	defer a.Unlock()
 	a.handler = h
+	a.updateDispatch()
 }
 
-// NewContextHTTP creates a new context for the given HTTP request
+// NewContextHTTP creates a new context for the given HTTP request.
+// The context gets its own copy of the App database connection (Copy/Close pattern),
+// which is released by Context.Close.
 func (a *App) NewContextHTTP(w http.ResponseWriter, r *http.Request) *Context {
-	//TODO(zareone): init DB and Codec
-	return &Context{
+	rw := newResponseWriter(w, r)
+	ctx := &Context{
 		App:            a,
 		Request:        r,
-		ResponseWriter: w,
+		ResponseWriter: rw,
+		rw:             rw,
 		Store:          kv.New(),
-		Codec:          json.Codec{},
+		DB:             a.DB.Repository,
 	}
+	if a.DB.Connection != nil {
+		ctx.Conn = a.DB.Connection.Copy()
+		if a.DB.NewRepository != nil {
+			ctx.DB = a.DB.NewRepository(ctx.Conn)
+		}
+	}
+	return ctx
 }
 
-// Run starts the app server
-func (a *App) Run() {
-	defer a.Close()
+// Close closes the database connection of this App instance (Copy/Close pattern)
+// and notifies the bootstrapped modules implementing the Closer interface, in reverse dependency order
+func (a *App) Close() error {
+	errs := &ShutdownError{}
+	if a.DB.Connection != nil {
+		errs.add(a.DB.Close())
+	}
+	for i := len(a.order) - 1; i >= 0; i-- {
+		name := a.order[i]
+		closer, ok := a.modules[name].(Closer)
+		if !ok {
+			continue
+		}
+		a.Log.Debugf("closing module %s\n", name)
+		if err := closer.Close(a); err != nil {
+			a.Log.Errorf("error closing module %s: %v\n", name, err)
+			errs.add(&ModuleError{Module: name, Op: "close", Err: err})
+		}
+	}
+	return errs.errOrNil()
+}
 
-	a.Log.Infof("### %s is starting...\n", a.name)
-	a.bootstrap()
-	a.Log.Infof("listening on port 8080...\n")
-	err := http.ListenAndServe(":8080", a.handler)
-	a.Log.Error(err)
-	os.Exit(1)
+// Start bootstraps the App modules and starts the ones implementing Starter, without serving HTTP
+// requests on any address. It allows serving them in-process through ServeHTTP, i.e. in tests.
+// The given context is cancelled to signal the started modules to finish. Call Shutdown when done.
+func (a *App) Start(ctx context.Context) error {
+	if err := a.bootstrap(); err != nil {
+		return err
+	}
+	return a.start(ctx)
 }
 
-// Close closes the database connection of this App instance (Copy/Close pattern)
-func (a *App) Close() {
-	a.DB.Close()
-}
-
-func (a *App) bootstrap() {
-	for level := 0; level <= 10; level++ {
-		for name, mod := range a.modules {
-			a.Log.Debugf("bootstrapping module %s at level %d\n", name, level)
-			err := mod.Bootstrap(a, level)
-			if err != nil {
-				panic(fmt.Sprintf("bootstrap error: module: %s, level: %d", name, level))
-			}
+// Shutdown stops the modules started with Start, giving each one the timeout to stop, and closes the App
+func (a *App) Shutdown(timeout time.Duration) error {
+	errs := &ShutdownError{}
+	errs.add(a.stop(timeout))
+	errs.add(a.Close())
+	return errs.errOrNil()
+}
+
+// bootstrap bootstraps the App modules, each one after its dependencies
+func (a *App) bootstrap() error {
+	order, err := sortModules(a.modules)
+	if err != nil {
+		return err
+	}
+	a.order = nil
+	for _, name := range order {
+		a.Log.Debugf("bootstrapping module %s\n", name)
+		if err := a.modules[name].Bootstrap(a); err != nil {
+			return &ModuleError{Module: name, Op: "bootstrap", Err: err}
+		}
+		a.order = append(a.order, name)
+	}
+	return nil
+}
+
+// start starts the bootstrapped modules implementing the Starter interface, in dependency order.
+// The given context is cancelled when the App begins to shut down.
+func (a *App) start(ctx context.Context) error {
+	a.started = nil
+	for _, name := range a.order {
+		starter, ok := a.modules[name].(Starter)
+		if !ok {
+			continue
+		}
+		a.Log.Debugf("starting module %s\n", name)
+		if err := starter.Start(ctx, a); err != nil {
+			return &ModuleError{Module: name, Op: "start", Err: err}
 		}
+		a.started = append(a.started, name)
+	}
+	return nil
+}
+
+// stop stops the started modules implementing the Stopper interface, in reverse dependency order.
+// Every module is given the timeout to stop; a module not returning before it is given up on.
+func (a *App) stop(timeout time.Duration) error {
+	errs := &ShutdownError{}
+	for i := len(a.started) - 1; i >= 0; i-- {
+		name := a.started[i]
+		stopper, ok := a.modules[name].(Stopper)
+		if !ok {
+			continue
+		}
+		a.Log.Debugf("stopping module %s\n", name)
+		if err := a.stopModule(stopper, timeout); err != nil {
+			a.Log.Errorf("error stopping module %s: %v\n", name, err)
+			errs.add(&ModuleError{Module: name, Op: "stop", Err: err})
+		}
+	}
+	a.started = nil
+	return errs.errOrNil()
+}
+
+func (a *App) stopModule(stopper Stopper, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- stopper.Stop(ctx, a) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
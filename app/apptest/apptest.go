@@ -0,0 +1,281 @@
+// Package apptest provides utilities for testing Apps in-process, without a database server
+// nor binding any address.
+//
+// A typical test builds the App, registers its routes and modules, and runs requests against it:
+//
+//	a := apptest.New(t)
+//	a.DB.RegisterResource(Todo{}, "todos", "")
+//	rest.Register(a.App, &Todo{}, "todos")
+//
+//	var todo Todo
+//	a.Post("/todos").BearerToken(token).JSON(Todo{Title: "test"}).Do().
+//		ExpectStatus(http.StatusCreated).
+//		ExpectHeader("Content-Type", "application/json").
+//		DecodeJSON(&todo)
+package apptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+	"github.com/syb-devs/goth/database"
+	"github.com/syb-devs/goth/database/driver/memory"
+	"github.com/syb-devs/goth/log"
+)
+
+// DefaultHost is the Host header of the requests, unless another one is set
+const DefaultHost = "example.com"
+
+// ShutdownTimeout is the time given to the started modules to stop once the test finishes
+var ShutdownTimeout = 5 * time.Second
+
+// App is an App set up for testing:
+//   - its database is an in-memory one, shared by all the requests
+//   - its Muxer is the stdmux one
+//   - it has empty "main" and "pub" middleware chains, which can be replaced with AddChain
+//   - it does not log anything
+//
+// Its modules are bootstrapped and started by the first request (or by calling Start),
+// and stopped when the test finishes.
+type App struct {
+	*app.App
+	// Conn is the connection to the in-memory database of the App
+	Conn *memory.Conn
+
+	t         testing.TB
+	startOnce sync.Once
+	started   bool
+}
+
+// New returns a new App for testing, which is shut down when the test finishes
+func New(t testing.TB) *App {
+	a := &App{
+		App: app.NewApp("apptest"),
+		t:   t,
+	}
+	a.Log = log.NilLogger{}
+	a.SetMuxer(stdmux.New(a.NewContextHTTP))
+	a.AddChain(chain.New(), "main")
+	a.AddChain(chain.New(), "pub")
+
+	a.Conn = memory.NewConnection(database.NewResourceMap())
+	a.DB.Connection = a.Conn
+	a.DB.ResourceMap = a.Conn.Map()
+	a.DB.Repository = memory.NewRepository(a.Conn)
+	a.DB.NewRepository = memory.RepositoryFactory
+
+	t.Cleanup(func() {
+		if !a.started {
+			a.Close()
+			return
+		}
+		if err := a.Shutdown(ShutdownTimeout); err != nil {
+			t.Errorf("error shutting down the app: %v", err)
+		}
+	})
+	return a
+}
+
+// Start bootstraps and starts the App modules, failing the test on error.
+// It is called by the first request, so it is only needed for checking the App before that.
+func (a *App) Start() {
+	a.t.Helper()
+	a.startOnce.Do(func() {
+		a.started = true
+		if err := a.App.Start(context.Background()); err != nil {
+			a.t.Fatalf("error starting the app: %v", err)
+		}
+	})
+}
+
+// Request returns a request for the given method and path, which can contain a query string
+func (a *App) Request(method, path string) *Request {
+	return &Request{
+		app:    a,
+		method: method,
+		path:   path,
+		header: make(http.Header),
+		host:   DefaultHost,
+	}
+}
+
+// Get returns a GET request for the given path
+func (a *App) Get(path string) *Request {
+	return a.Request(http.MethodGet, path)
+}
+
+// Post returns a POST request for the given path
+func (a *App) Post(path string) *Request {
+	return a.Request(http.MethodPost, path)
+}
+
+// Put returns a PUT request for the given path
+func (a *App) Put(path string) *Request {
+	return a.Request(http.MethodPut, path)
+}
+
+// Patch returns a PATCH request for the given path
+func (a *App) Patch(path string) *Request {
+	return a.Request(http.MethodPatch, path)
+}
+
+// Delete returns a DELETE request for the given path
+func (a *App) Delete(path string) *Request {
+	return a.Request(http.MethodDelete, path)
+}
+
+// Request is a request to be run against an App. Its methods can be chained, finishing with Do.
+type Request struct {
+	app    *App
+	method string
+	path   string
+	host   string
+	header http.Header
+	query  url.Values
+	body   []byte
+}
+
+// Header sets a header of the request
+func (r *Request) Header(name, value string) *Request {
+	r.header.Set(name, value)
+	return r
+}
+
+// Host sets the Host header of the request, i.e. for routing it to a virtual host
+func (r *Request) Host(host string) *Request {
+	r.host = host
+	return r
+}
+
+// BearerToken sets the Authorization header of the request with the given bearer token
+func (r *Request) BearerToken(token string) *Request {
+	return r.Header("Authorization", "Bearer "+token)
+}
+
+// Query adds a query string parameter to the request
+func (r *Request) Query(name, value string) *Request {
+	if r.query == nil {
+		r.query = make(url.Values)
+	}
+	r.query.Add(name, value)
+	return r
+}
+
+// Body sets the body of the request and its Content-Type header
+func (r *Request) Body(contentType string, body []byte) *Request {
+	r.body = body
+	return r.Header("Content-Type", contentType)
+}
+
+// JSON sets the body of the request to the JSON encoding of the given value.
+// It fails the test if the value can not be encoded.
+func (r *Request) JSON(v interface{}) *Request {
+	r.app.t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		r.app.t.Fatalf("error encoding the JSON body of %s %s: %v", r.method, r.path, err)
+	}
+	return r.Body("application/json", body)
+}
+
+// Do runs the request against the App, starting it if needed, and returns the response
+func (r *Request) Do() *Response {
+	r.app.t.Helper()
+	r.app.Start()
+
+	req := httptest.NewRequest(r.method, r.path, bytes.NewReader(r.body))
+	req.Host = r.host
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if len(r.query) > 0 {
+		q := req.URL.Query()
+		for name, values := range r.query {
+			q[name] = append(q[name], values...)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	rec := httptest.NewRecorder()
+	r.app.ServeHTTP(rec, req)
+	return &Response{
+		StatusCode: rec.Code,
+		Header:     rec.Result().Header,
+		Body:       rec.Body.Bytes(),
+		t:          r.app.t,
+		name:       r.method + " " + r.path,
+	}
+}
+
+// Response is the response to a Request. Its assertion methods can be chained.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	t    testing.TB
+	name string
+}
+
+// ExpectStatus checks the status code of the response
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Errorf("%s: expecting status %d, got %d (body: %s)", r.name, code, r.StatusCode, r.Body)
+	}
+	return r
+}
+
+// ExpectHeader checks the value of a header of the response. An empty value checks the header is not set.
+func (r *Response) ExpectHeader(name, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(name); got != value {
+		r.t.Errorf("%s: expecting header %s %q, got %q", r.name, name, value, got)
+	}
+	return r
+}
+
+// DecodeJSON decodes the JSON response body into the given value, failing the test on error
+func (r *Response) DecodeJSON(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("%s: error decoding the JSON body %s: %v", r.name, r.Body, err)
+	}
+	return r
+}
+
+// ExpectJSON checks the JSON response body is equivalent to the JSON encoding of the given value,
+// regardless of the order of the object keys and the formatting
+func (r *Response) ExpectJSON(v interface{}) *Response {
+	r.t.Helper()
+	expected, err := json.Marshal(v)
+	if err != nil {
+		r.t.Fatalf("%s: error encoding the expected JSON body: %v", r.name, err)
+	}
+	var want, got interface{}
+	json.Unmarshal(expected, &want)
+	if err := json.Unmarshal(r.Body, &got); err != nil {
+		r.t.Errorf("%s: expecting JSON body %s, got %s (%v)", r.name, expected, r.Body, err)
+		return r
+	}
+	if !reflect.DeepEqual(want, got) {
+		r.t.Errorf("%s: expecting JSON body %s, got %s", r.name, expected, r.Body)
+	}
+	return r
+}
+
+// Text returns the response body as a string
+func (r *Response) Text() string {
+	return string(r.Body)
+}
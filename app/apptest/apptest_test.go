@@ -0,0 +1,138 @@
+package apptest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/apptest"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+	"github.com/syb-devs/goth/database/driver/mongodb"
+	"github.com/syb-devs/goth/health"
+	"github.com/syb-devs/goth/rest"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Todo struct {
+	mongodb.Resource `bson:",inline" json:",inline"`
+	Title            string `bson:"title" json:"title"`
+}
+
+type testUser struct {
+	id bson.ObjectId
+}
+
+func (u testUser) GetID() interface{}  { return u.id }
+func (u testUser) GetIDString() string { return u.id.Hex() }
+func (u testUser) GetEmail() string    { return "john@example.com" }
+
+// bearerAuth authenticates the requests with the given token as the given user
+func bearerAuth(token string, u app.User) app.Middleware {
+	return func(next app.Handler) app.Handler {
+		return app.HandlerFunc(func(ctx *app.Context) error {
+			if ctx.Request.Header.Get("Authorization") != "Bearer "+token {
+				return app.ErrAccessDenied
+			}
+			ctx.User = u
+			return next.Serve(ctx)
+		})
+	}
+}
+
+func TestREST(t *testing.T) {
+	a := apptest.New(t)
+	a.AddChain(chain.New(bearerAuth("s3cr3t", testUser{bson.NewObjectId()})), "main")
+	a.DB.RegisterResource(Todo{}, "todos", "")
+	rest.Register(a.App, &Todo{}, "todos")
+
+	a.Post("/todos").JSON(Todo{Title: "test"}).Do().
+		ExpectStatus(http.StatusForbidden)
+
+	// Nothing is stored when the response can not be encoded
+	a.Post("/todos").BearerToken("s3cr3t").Header("Accept", "text/html").JSON(Todo{Title: "test"}).Do().
+		ExpectStatus(http.StatusNotAcceptable)
+	a.Get("/todos").BearerToken("s3cr3t").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSON([]Todo{})
+
+	var created Todo
+	res := a.Post("/todos").BearerToken("s3cr3t").JSON(Todo{Title: "test"}).Do().
+		ExpectStatus(http.StatusCreated).
+		ExpectHeader("Content-Type", "application/json").
+		DecodeJSON(&created)
+	if !created.ID.Valid() {
+		t.Fatalf("expecting the created todo to have an ID, got %s", res.Body)
+	}
+	res.ExpectHeader("Location", "/todos/"+created.ID.Hex())
+
+	// Timestamps are stored with millisecond precision, as in MongoDB
+	var stored Todo
+	a.Get("/todos/" + created.ID.Hex()).BearerToken("s3cr3t").Do().
+		ExpectStatus(http.StatusOK).
+		DecodeJSON(&stored)
+	if stored.ID != created.ID || stored.Title != created.Title {
+		t.Errorf("expecting todo %+v, got %+v", created, stored)
+	}
+	a.Get("/todos").Query("limit", "10").BearerToken("s3cr3t").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSON([]Todo{stored})
+	a.Delete("/todos/" + created.ID.Hex()).BearerToken("s3cr3t").Do().
+		ExpectStatus(http.StatusOK)
+	a.Get("/todos/" + created.ID.Hex()).BearerToken("s3cr3t").Do().
+		ExpectStatus(http.StatusNotFound)
+}
+
+func TestRESTMountTwice(t *testing.T) {
+	a := apptest.New(t)
+	a.AddChain(chain.New(bearerAuth("s3cr3t", testUser{bson.NewObjectId()})), "main")
+	a.DB.RegisterResource(Todo{}, "todos", "")
+	crud := rest.New(&Todo{})
+	rest.Mount(a.Group("/v1", "main"), crud, "todos")
+	rest.Mount(a.Group("/v2", "main"), crud, "todos")
+
+	for _, version := range []string{"v1", "v2"} {
+		var created Todo
+		a.Post("/"+version+"/todos").BearerToken("s3cr3t").JSON(Todo{Title: "test"}).Do().
+			ExpectStatus(http.StatusCreated).
+			DecodeJSON(&created).
+			ExpectHeader("Location", "/"+version+"/todos/"+created.ID.Hex())
+		if _, err := a.URL(version+".todos.retrieve", app.URLParams{"resource_id": created.ID.Hex()}); err != nil {
+			t.Errorf("expecting the %s retrieve route to be named, got %v", version, err)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	a := apptest.New(t)
+	a.Use(health.NewModule(health.Options{}))
+
+	var report health.Report
+	a.Get(health.DefaultReadinessPath).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Cache-Control", "no-store").
+		DecodeJSON(&report)
+	if _, ok := report.Checks[health.DatabaseCheck]; !ok {
+		t.Errorf("expecting the %s check to be run, got %+v", health.DatabaseCheck, report)
+	}
+}
+
+func TestHost(t *testing.T) {
+	a := apptest.New(t)
+	echo := func(body string) app.HandlerFunc {
+		return func(ctx *app.Context) error {
+			_, err := ctx.Write([]byte(body + ctx.URLParams.ByName("tenant")))
+			return err
+		}
+	}
+	a.Handle("GET", "/", echo("default"))
+	a.Host(":tenant.example.com", stdmux.New(a.NewContextHTTP)).Handle("GET", "/", echo("tenant "))
+
+	if body := a.Get("/").Do().ExpectStatus(http.StatusOK).Text(); body != "default" {
+		t.Errorf("expecting body %q, got %q", "default", body)
+	}
+	if body := a.Get("/").Host("acme.example.com").Do().Text(); body != "tenant acme" {
+		t.Errorf("expecting body %q, got %q", "tenant acme", body)
+	}
+}
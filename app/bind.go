@@ -0,0 +1,219 @@
+package app
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/syb-devs/goth/validate"
+)
+
+// MaxFormMemory is the maximum number of bytes of a multipart form kept in memory by Bind.
+// The rest of the files are stored in temporary files.
+var MaxFormMemory int64 = 32 << 20
+
+// MaxFormSize is the maximum size in bytes of the form request bodies parsed by Bind.
+// Larger bodies are returned as ErrFormTooLarge.
+var MaxFormSize int64 = 32 << 20
+
+var (
+	// ErrBindTarget is returned by Bind when the destination is not a pointer to a struct
+	ErrBindTarget = errors.New("bind destination must be a pointer to a struct")
+	// ErrFormTooLarge is returned by Bind when the form request body exceeds MaxFormSize
+	ErrFormTooLarge = &HTTPError{Status: http.StatusRequestEntityTooLarge, Code: "form_too_large", Message: "form exceeds the size limit"}
+)
+
+// bindSources are the struct tags used by Bind, in the order they are applied
+var bindSources = []string{"form", "query", "header", "param"}
+
+// Bind fills the struct pointed by dest with the data of the request, and validates it with the
+// rules of its validate struct tags. The struct fields are filled, in this order, from:
+//
+//   - the request body, decoded with the Codec for its Content-Type, unless it is a form
+//   - the form values, for the fields with a form:"name" tag
+//   - the query string, for the fields with a query:"name" tag
+//   - the request headers, for the fields with a header:"Name" tag
+//   - the URL params, for the fields with a param:"name" tag
+//
+// Tagged fields can be strings, byte slices, booleans, numbers, encoding.TextUnmarshalers, or slices
+// and pointers of them. Embedded structs are filled too.
+//
+// A malformed body is returned as a 400 Bad Request HTTPError. The values which can not be
+// converted to the field type, and the validation failures, are returned together as a
+// *validate.ValidationError, which is rendered as a 422 Unprocessable Entity response.
+func (ctx *Context) Bind(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return ErrBindTarget
+	}
+
+	values := make(map[string]func(name string) []string, len(bindSources))
+	if isForm(ctx.Request) {
+		if err := parseForm(ctx.ResponseWriter, ctx.Request); err != nil {
+			if isTooLarge(err) {
+				return ErrFormTooLarge
+			}
+			return &HTTPError{Status: http.StatusBadRequest, Code: "invalid_body", Message: "malformed form", Err: err}
+		}
+		values["form"] = func(name string) []string { return ctx.Request.Form[name] }
+	} else if hasBody(ctx.Request) {
+		if err := ctx.Decode(dest); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	query := ctx.Request.URL.Query()
+	values["query"] = func(name string) []string { return query[name] }
+	values["header"] = func(name string) []string { return ctx.Request.Header.Values(name) }
+	values["param"] = func(name string) []string {
+		if value, ok := ctx.URLParams[name]; ok {
+			return []string{value}
+		}
+		return nil
+	}
+
+	errs := make(validate.FieldErrors)
+	for _, source := range bindSources {
+		if get, ok := values[source]; ok {
+			bindValues(v.Elem(), source, get, errs)
+		}
+	}
+
+	result := validate.New().Validate(dest)
+	if result.LogicError != nil {
+		return result.LogicError
+	}
+	for field, ferrs := range result.FieldErrors {
+		errs.AppendErrors(field, ferrs...)
+	}
+	if len(errs) > 0 {
+		return &validate.ValidationError{FieldErrors: errs}
+	}
+	return nil
+}
+
+// bindValues sets the struct fields tagged with the given source, recursing into the embedded
+// structs, and adds the conversion failures to errs
+func bindValues(sv reflect.Value, source string, get func(name string) []string, errs validate.FieldErrors) {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		fv := sv.Field(i)
+		name, tagged := field.Tag.Lookup(source)
+		if !tagged {
+			if field.Anonymous && fv.Kind() == reflect.Struct {
+				bindValues(fv, source, get, errs)
+			}
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		vals := get(name)
+		if len(vals) == 0 {
+			continue
+		}
+		if err := setField(fv, vals); err != nil {
+			errs.AppendErrors(field.Name, fmt.Errorf("invalid value for %s %s: %v", source, name, err))
+		}
+	}
+}
+
+// setField sets a field from its string values, converting them to the field type
+func setField(fv reflect.Value, vals []string) error {
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), vals); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+	if _, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); !ok && fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setField(slice.Index(i), []string{val}); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setValue(fv, vals[0])
+}
+
+// setValue sets a non slice field from a string value
+func setValue(fv reflect.Value, val string) error {
+	if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(val))
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return errors.New("must be a positive integer")
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported field type %s", fv.Type())
+		}
+		fv.SetBytes([]byte(val))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// isForm checks if the request body is an URL encoded or multipart form
+func isForm(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
+}
+
+// parseForm parses the form of the request, limiting the size of its body to MaxFormSize
+func parseForm(w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxFormSize)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		return r.ParseMultipartForm(MaxFormMemory)
+	}
+	return r.ParseForm()
+}
+
+// isTooLarge checks if the error was returned for a body exceeding the http.MaxBytesReader limit
+func isTooLarge(err error) bool {
+	return strings.Contains(err.Error(), "request body too large")
+}
+
+// hasBody checks if the request may have a body to decode
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
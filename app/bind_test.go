@@ -0,0 +1,193 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/log"
+	_ "github.com/syb-devs/goth/validate/required"
+)
+
+type paging struct {
+	Limit int `query:"limit"`
+}
+
+type todoInput struct {
+	paging
+	ID       int        `param:"id"`
+	Title    string     `json:"title" form:"title" validate:"required"`
+	Tags     []string   `query:"tag"`
+	Done     *bool      `json:"done" query:"done"`
+	Since    time.Time  `query:"since"`
+	Tenant   string     `header:"X-Tenant"`
+	Priority float64    `json:"priority" form:"priority"`
+	ignored  string     `query:"ignored"`
+	Skipped  string     `query:"-"`
+	Due      *time.Time `json:"due"`
+}
+
+func TestBind(t *testing.T) {
+	done, since := true, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		contentType string
+		body        string
+		query       string
+		expected    todoInput
+	}{
+		{
+			"application/json",
+			`{"title": "write tests", "priority": 1.5, "done": false}`,
+			"?limit=10&tag=a&tag=b&done=true&since=2026-10-01T00:00:00Z&ignored=x&Skipped=x",
+			todoInput{paging: paging{10}, ID: 42, Title: "write tests", Tags: []string{"a", "b"}, Done: &done, Since: since, Tenant: "acme", Priority: 1.5},
+		},
+		{
+			"application/x-www-form-urlencoded",
+			"title=write+tests&priority=2",
+			"",
+			todoInput{ID: 42, Title: "write tests", Tenant: "acme", Priority: 2},
+		},
+		{
+			"",
+			"",
+			"?title=ignored",
+			todoInput{ID: 42, Tenant: "acme"},
+		},
+	}
+
+	a := app.NewApp("test")
+	for i, test := range tests {
+		req := httptest.NewRequest("POST", "/todos/42"+test.query, strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		req.Header.Set("X-Tenant", "acme")
+		ctx := a.NewContextHTTP(httptest.NewRecorder(), req)
+		ctx.URLParams = app.URLParams{"id": "42"}
+
+		var input todoInput
+		err := ctx.Bind(&input)
+		if test.expected.Title == "" {
+			if err == nil {
+				t.Errorf("test #%d: expecting a validation error", i+1)
+			}
+		} else if err != nil {
+			t.Errorf("test #%d: unexpected error %v", i+1, err)
+		}
+		if !reflect.DeepEqual(input, test.expected) {
+			t.Errorf("test #%d: expecting %+v, got %+v", i+1, test.expected, input)
+		}
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	tests := []struct {
+		body    string
+		query   string
+		status  int
+		details map[string]int
+	}{
+		{`{"title": "ok"}`, "", http.StatusOK, nil},
+		{`{"title": ""}`, "?limit=ten", http.StatusUnprocessableEntity, map[string]int{"Title": 1, "Limit": 1}},
+		{`{"title": "ok"}`, "?since=yesterday&done=maybe", http.StatusUnprocessableEntity, map[string]int{"Since": 1, "Done": 1}},
+		{`{"title": `, "", http.StatusBadRequest, nil},
+	}
+
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	h := app.HandlerFunc(func(ctx *app.Context) error {
+		var input todoInput
+		return ctx.Bind(&input)
+	})
+
+	for i, test := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/todos"+test.query, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		app.Dispatch(a.NewContextHTTP(rec, req), h)
+
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, rec.Code)
+		}
+		if test.details == nil {
+			continue
+		}
+		var p struct {
+			Details map[string][]string `json:"details"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatalf("test #%d: error decoding problem: %v", i+1, err)
+		}
+		got := make(map[string]int, len(p.Details))
+		for field, errs := range p.Details {
+			got[field] = len(errs)
+		}
+		if !reflect.DeepEqual(got, test.details) {
+			t.Errorf("test #%d: expecting errors by field %v, got %v", i+1, test.details, p.Details)
+		}
+	}
+
+	defer func(size int64) { app.MaxFormSize = size }(app.MaxFormSize)
+	app.MaxFormSize = 16
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/todos", strings.NewReader("title=a+title+too+long+for+the+limit"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	app.Dispatch(a.NewContextHTTP(rec, req), h)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expecting status %d for a form over MaxFormSize, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+
+	ctx := a.NewContextHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if err := ctx.Bind(todoInput{}); err != app.ErrBindTarget {
+		t.Errorf("expecting error %v, got %v", app.ErrBindTarget, err)
+	}
+}
+
+func TestBindRemovesFormFiles(t *testing.T) {
+	defer func(size int64) { app.MaxFormMemory = size }(app.MaxFormMemory)
+	app.MaxFormMemory = 1
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	mw.WriteField("title", "upload")
+	fw, err := mw.CreateFormFile("attachment", "notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("stored in a temporary file"))
+	mw.Close()
+
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	h := app.HandlerFunc(func(ctx *app.Context) error {
+		// Handlers can replace the request with a shallow copy
+		ctx.WithValue("key", "value")
+		var in todoInput
+		return ctx.Bind(&in)
+	})
+	req := httptest.NewRequest("POST", "/todos", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	app.Dispatch(a.NewContextHTTP(rec, req), h)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expecting status %d, got %d", http.StatusOK, rec.Code)
+	}
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expecting the temporary files to be removed, got %d", len(entries))
+	}
+}
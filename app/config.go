@@ -0,0 +1,438 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/syb-devs/goth/validate"
+)
+
+// Supported configuration file formats
+const (
+	ConfigFormatJSON = "json"
+	ConfigFormatYAML = "yaml"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ConfigError is returned when a configuration section does not pass validation
+type ConfigError struct {
+	Section string
+	Errors  validate.FieldErrors
+}
+
+// Error returns a literal representation of the configuration errors
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration for section %s: %s", e.Section, strings.TrimSpace(e.Errors.String()))
+}
+
+// Config holds layered application settings, grouped in sections.
+// Values are resolved in the following order, each layer overriding the previous one:
+// defaults, configuration files and environment variables.
+type Config struct {
+	mu        sync.RWMutex
+	defaults  map[string]interface{}
+	values    map[string]interface{}
+	envPrefix string
+	lookupEnv func(string) (string, bool)
+	validator *validate.Validator
+}
+
+// NewConfig allocates and returns an empty Config
+func NewConfig() *Config {
+	return &Config{
+		defaults:  make(map[string]interface{}),
+		values:    make(map[string]interface{}),
+		lookupEnv: os.LookupEnv,
+		validator: validate.New(),
+	}
+}
+
+// SetEnvPrefix sets the prefix used for the environment variable names
+func (c *Config) SetEnvPrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.envPrefix = prefix
+}
+
+// SetDefaults sets the default values for a section.
+// The given value can be a map or a struct, and it's encoded using its json tags.
+// Only the non zero fields of a struct are taken as defaults, so the zero ones do not
+// override the values already present in the Section destination.
+func (c *Config) SetDefaults(section string, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	var tree interface{}
+	if rv.Kind() == reflect.Struct {
+		tree = structTree(rv)
+	} else {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(data, &tree); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	setPath(c.defaults, section, tree)
+	return nil
+}
+
+// LoadFile reads a JSON or YAML configuration file, using its extension to pick the format,
+// and merges its contents over the values already loaded
+func (c *Config) LoadFile(path string) error {
+	var format string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = ConfigFormatJSON
+	case ".yaml", ".yml":
+		format = ConfigFormatYAML
+	default:
+		return fmt.Errorf("unsupported configuration file format: %s", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Load(f, format)
+}
+
+// Load reads configuration data in the given format and merges it over the values already loaded
+func (c *Config) Load(r io.Reader, format string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	tree := make(map[string]interface{})
+	switch format {
+	case ConfigFormatJSON:
+		err = json.Unmarshal(data, &tree)
+	case ConfigFormatYAML:
+		var raw map[interface{}]interface{}
+		if err = yaml.Unmarshal(data, &raw); err == nil && raw != nil {
+			tree = normalizeYAML(raw).(map[string]interface{})
+		}
+	default:
+		err = fmt.Errorf("unsupported configuration format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	merge(c.values, tree)
+	return nil
+}
+
+// Section decodes the settings of the given section into dest, which must be a pointer to a struct.
+// Field values already present in dest are kept unless overridden by any configuration layer.
+// Nested section names are separated by dots (e.g. "goth.user").
+// Environment variables are named after the env prefix, the section and the json name of the
+// field (e.g. GOTH_USER_SECRET), unless an explicit name is given with the env struct tag.
+// Once decoded, the struct is checked using the validate package rules.
+func (c *Config) Section(name string, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config section %s: destination must be a pointer to a struct, %T given", name, dest)
+	}
+
+	c.mu.RLock()
+	layers := []interface{}{getPath(c.defaults, name), getPath(c.values, name)}
+	prefix := c.envPrefix
+	c.mu.RUnlock()
+
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		if err := decodeTree(v.Elem(), layer); err != nil {
+			return fmt.Errorf("config section %s: %w", name, err)
+		}
+	}
+	if err := c.applyEnv(v.Elem(), envName(prefix, name)); err != nil {
+		return fmt.Errorf("config section %s: %w", name, err)
+	}
+
+	res := c.validator.Validate(dest)
+	if res.LogicError != nil {
+		return fmt.Errorf("config section %s: %w", name, res.LogicError)
+	}
+	if !res.OK() {
+		return &ConfigError{Section: name, Errors: res.FieldErrors}
+	}
+	return nil
+}
+
+// structTree returns the tree of the non zero fields of a struct, by json name
+func structTree(v reflect.Value) map[string]interface{} {
+	tree := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := jsonName(field)
+		fv := v.Field(i)
+		if name == "-" || fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Struct && field.Type != durationType {
+			sub := structTree(fv)
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				merge(tree, sub)
+			} else {
+				tree[name] = sub
+			}
+			continue
+		}
+		tree[name] = fv.Interface()
+	}
+	return tree
+}
+
+// decodeTree stores the values of a configuration tree node into v, keeping the struct fields
+// not present in the node. Durations can be given as strings (e.g. "5s") or nanoseconds.
+func decodeTree(v reflect.Value, node interface{}) error {
+	if v.Type() == durationType {
+		if s, ok := node.(string); ok {
+			return setFromString(v, s)
+		}
+	}
+	m, ok := node.(map[string]interface{})
+	if !ok || v.Kind() != reflect.Struct || v.Type() == durationType {
+		data, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" && fv.Kind() == reflect.Struct {
+			if err := decodeTree(fv, m); err != nil {
+				return err
+			}
+			continue
+		}
+		val, ok := lookupKey(m, name)
+		if !ok {
+			continue
+		}
+		if err := decodeTree(fv, val); err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// lookupKey finds a key in a tree node, preferring an exact match but ignoring the case
+// like encoding/json does
+func lookupKey(m map[string]interface{}, key string) (interface{}, bool) {
+	if val, ok := m[key]; ok {
+		return val, true
+	}
+	for k, val := range m {
+		if strings.EqualFold(k, key) {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// applyEnv overrides the struct fields with the values of the matching environment variables
+func (c *Config) applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && field.Type != durationType {
+			if err := c.applyEnv(fv, envName(prefix, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		key := field.Tag.Get("env")
+		if key == "" {
+			key = envName(prefix, name)
+		}
+		val, ok := c.lookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setFromString(fv, val); err != nil {
+			return fmt.Errorf("environment variable %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// setFromString parses a string and stores it in the given value, according to its type
+func setFromString(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s))
+			return nil
+		}
+		parts := strings.Split(s, ",")
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setFromString(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// envName builds an environment variable name by joining the given parts in upper case,
+// replacing any non alphanumeric character with an underscore
+func envName(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	name := strings.ToUpper(strings.Join(nonEmpty, "_"))
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// getPath returns the node found in a tree for a dot separated path
+func getPath(tree map[string]interface{}, path string) interface{} {
+	var node interface{} = tree
+	for _, key := range strings.Split(path, ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[key]
+	}
+	return node
+}
+
+// setPath merges a value into a tree in the given dot separated path
+func setPath(tree map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		sub, ok := tree[key].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			tree[key] = sub
+		}
+		tree = sub
+	}
+	merge(tree, map[string]interface{}{keys[len(keys)-1]: value})
+}
+
+// merge deep merges the src tree into dst
+func merge(dst, src map[string]interface{}) {
+	for key, val := range src {
+		if m, ok := val.(map[string]interface{}); ok {
+			if sub, ok := dst[key].(map[string]interface{}); ok {
+				merge(sub, m)
+				continue
+			}
+		}
+		dst[key] = val
+	}
+}
+
+// normalizeYAML converts the maps decoded by the YAML parser into map[string]interface{},
+// so they can be handled like the JSON decoded ones
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalizeYAML(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
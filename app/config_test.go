@@ -0,0 +1,114 @@
+package app_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	_ "github.com/syb-devs/goth/validate/required"
+)
+
+type dbConfig struct {
+	URL      string        `json:"url"`
+	Database string        `json:"database" validate:"required"`
+	Timeout  time.Duration `json:"timeout"`
+	Hosts    []string      `json:"hosts"`
+	Secret   string        `json:"secret" env:"TEST_DB_SECRET"`
+}
+
+func TestConfigLayers(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "config.json")
+	yamlFile := filepath.Join(dir, "config.yaml")
+	writeFile(t, jsonFile, `{"db": {"main": {"url": "mongodb://json", "database": "jsondb"}}}`)
+	writeFile(t, yamlFile, "db:\n  main:\n    database: yamldb\n    hosts: [a, b]\n")
+
+	t.Setenv("GOTH_DB_MAIN_TIMEOUT", "5s")
+	t.Setenv("TEST_DB_SECRET", "s3cret")
+
+	cfg := app.NewConfig()
+	cfg.SetEnvPrefix("goth")
+	if err := cfg.SetDefaults("db.main", dbConfig{URL: "mongodb://default", Timeout: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{jsonFile, yamlFile} {
+		if err := cfg.LoadFile(f); err != nil {
+			t.Fatalf("LoadFile(%s): %v", f, err)
+		}
+	}
+
+	var actual dbConfig
+	if err := cfg.Section("db.main", &actual); err != nil {
+		t.Fatalf("Section: %v", err)
+	}
+	expected := dbConfig{
+		URL:      "mongodb://json",
+		Database: "yamldb",
+		Timeout:  5 * time.Second,
+		Hosts:    []string{"a", "b"},
+		Secret:   "s3cret",
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expecting config %+v, got %+v", expected, actual)
+	}
+}
+
+func TestConfigFileDurationsAndPresets(t *testing.T) {
+	cfg := app.NewConfig()
+	if err := cfg.Load(strings.NewReader(`{"db": {"timeout": "5s"}}`), app.ConfigFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Load(strings.NewReader("cache:\n  timeout: 250ms\n"), app.ConfigFormatYAML); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SetDefaults("db", dbConfig{URL: "mongodb://default"}); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := dbConfig{Database: "preset", Secret: "preset"}
+	if err := cfg.Section("db", &actual); err != nil {
+		t.Fatalf("Section: %v", err)
+	}
+	expected := dbConfig{URL: "mongodb://default", Database: "preset", Timeout: 5 * time.Second, Secret: "preset"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expecting config %+v, got %+v", expected, actual)
+	}
+
+	cache := dbConfig{Database: "cache"}
+	if err := cfg.Section("cache", &cache); err != nil {
+		t.Fatalf("Section: %v", err)
+	}
+	if cache.Timeout != 250*time.Millisecond {
+		t.Errorf("expecting timeout %v, got %v", 250*time.Millisecond, cache.Timeout)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	cfg := app.NewConfig()
+	err := cfg.Section("db", &dbConfig{})
+	cerr, ok := err.(*app.ConfigError)
+	if !ok {
+		t.Fatalf("expecting a *app.ConfigError, got %v", err)
+	}
+	if len(cerr.Errors.FieldErrors("Database")) == 0 {
+		t.Errorf("expecting a validation error for the Database field, got %v", cerr)
+	}
+}
+
+func TestConfigUnsupportedFile(t *testing.T) {
+	cfg := app.NewConfig()
+	err := cfg.LoadFile("config.ini")
+	if err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("expecting unsupported format error, got %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,33 +1,110 @@
 package app
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"io"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/syb-devs/goth/database"
 	"github.com/syb-devs/goth/encoding"
 	"github.com/syb-devs/goth/kv"
 )
 
+// DefaultMediaType is the media type of the requests without a Content-Type header, and the preferred
+// one for the responses when the Accept header of the request allows several of the registered ones
+const DefaultMediaType = "application/json"
+
+// ErrHijackUnsupported is returned when hijacking the connection of a request whose
+// ResponseWriter does not implement http.Hijacker
+var ErrHijackUnsupported = errors.New("connection hijacking not supported by the response writer")
+
 // CtxGenHTTP is a function that generates contexts from a pair
 // of HTTP Request and ResponseWriter
 type CtxGenHTTP func(w http.ResponseWriter, r *http.Request) *Context
 
 // Context represents the isolated context for one request
 type Context struct {
-	App     *App
-	Conn    database.Connection
+	App *App
+	// Conn is the database connection for the request, a copy of the App one
+	Conn database.Connection
+	// DB is the database Repository for the request, bound to Conn
+	DB      database.Repository
 	Request *http.Request
 	http.ResponseWriter
 	URLParams URLParams
-	Codec     encoding.Codec
-	User      User
+	// Codec, if set, is used for decoding the request and encoding the response, instead of the
+	// registered codecs picked by the Content-Type and Accept request headers
+	Codec encoding.Codec
+	User  User
 	*kv.Store
+
+	rw      *responseWriter
+	streams []*SSEStream
+	encoder *negotiatedCodec
 }
 
-// Close performs clean-up tasks for the Context
+// Close performs clean-up tasks for the Context, such as releasing the database connection,
+// closing the SSE streams and removing the temporary files of the multipart form
 func (ctx *Context) Close() {
-	ctx.Conn.Close()
+	for _, s := range ctx.streams {
+		s.Close()
+	}
+	ctx.streams = nil
+	// net/http only removes the form files of the original request, and ctx.Request can be a copy
+	if ctx.Request != nil && ctx.Request.MultipartForm != nil {
+		ctx.Request.MultipartForm.RemoveAll()
+	}
+	if ctx.Conn != nil {
+		ctx.Conn.Close()
+		ctx.Conn = nil
+	}
+}
+
+// Context returns the context.Context of the request. It is cancelled when the client
+// disconnects or the request deadline is exceeded.
+// It should be passed to any blocking call made on behalf of the request (database, outbound HTTP...).
+func (ctx *Context) Context() context.Context {
+	if ctx.Request == nil {
+		return context.Background()
+	}
+	return ctx.Request.Context()
+}
+
+// SetContext replaces the context.Context of the request
+func (ctx *Context) SetContext(c context.Context) {
+	ctx.Request = ctx.Request.WithContext(c)
+}
+
+// WithValue sets a value in the request context.Context, so it is available
+// to any code receiving it
+func (ctx *Context) WithValue(key, val interface{}) {
+	ctx.SetContext(context.WithValue(ctx.Context(), key, val))
+}
+
+// WithTimeout sets a deadline in the request context.Context. The returned function
+// should be called to release the resources once the work is done.
+func (ctx *Context) WithTimeout(timeout time.Duration) context.CancelFunc {
+	c, cancel := context.WithTimeout(ctx.Context(), timeout)
+	ctx.SetContext(c)
+	return cancel
+}
+
+// Written returns true if the response status has already been sent to the client
+func (ctx *Context) Written() bool {
+	return ctx.rw != nil && ctx.rw.written
+}
+
+// Status returns the response status code sent to the client, or zero if not sent yet
+func (ctx *Context) Status() int {
+	if ctx.rw == nil {
+		return 0
+	}
+	return ctx.rw.status
 }
 
 // WriteString writes the given string in the Context's ResponseWriter
@@ -35,17 +112,138 @@ func (ctx *Context) WriteString(s string) (int, error) {
 	return io.WriteString(ctx.ResponseWriter, s)
 }
 
+// Hijack lets the caller take over the connection of the request (i.e. for protocol upgrades),
+// bypassing any wrapping of the response by the middlewares. It makes Context an http.Hijacker.
+// It returns ErrHijackUnsupported if the connection can not be hijacked.
+func (ctx *Context) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if ctx.rw != nil {
+		return ctx.rw.Hijack()
+	}
+	if h, ok := ctx.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, ErrHijackUnsupported
+}
+
 // URLParam returns the value for the requested URL parameter
 func (ctx *Context) URLParam(name string) string {
 	return ctx.URLParams.ByName(name)
 }
 
-// Decode decodes data from the context request into the destination type
+// Decoder returns the Codec for decoding the request body, registered for the media type
+// of its Content-Type header, or for DefaultMediaType if it has none.
+// It returns ErrUnsupportedMediaType if no Codec is registered for the media type.
+func (ctx *Context) Decoder() (encoding.Codec, error) {
+	if ctx.Codec != nil {
+		return ctx.Codec, nil
+	}
+	contentType := ctx.Request.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = DefaultMediaType
+	}
+	codec, ok := encoding.Lookup(contentType)
+	if !ok {
+		return nil, ErrUnsupportedMediaType
+	}
+	return codec, nil
+}
+
+// Encoder returns the media type and the Codec for encoding the response, negotiated from the
+// Accept request header, preferring DefaultMediaType. The media type is empty if the Context
+// Codec is set. It returns ErrNotAcceptable if no registered media type is acceptable.
+// The negotiation happens once per Context, so handlers with side effects can call it before
+// them, to fail early if the response could not be encoded.
+func (ctx *Context) Encoder() (string, encoding.Codec, error) {
+	if ctx.Codec != nil {
+		return "", ctx.Codec, nil
+	}
+	if ctx.encoder == nil {
+		mediaType, codec, ok := encoding.Negotiate(ctx.Request.Header.Get("Accept"), DefaultMediaType)
+		ctx.encoder = &negotiatedCodec{mediaType: mediaType, codec: codec}
+		if !ok {
+			ctx.encoder = &negotiatedCodec{err: ErrNotAcceptable}
+		}
+	}
+	return ctx.encoder.mediaType, ctx.encoder.codec, ctx.encoder.err
+}
+
+// negotiatedCodec is the result of the response Codec negotiation, cached in the Context
+type negotiatedCodec struct {
+	mediaType string
+	codec     encoding.Codec
+	err       error
+}
+
+// Decode decodes data from the context request into the destination type, using the Codec
+// for its Content-Type (see Decoder). A malformed or empty body is returned as a 400 Bad Request
+// HTTPError wrapping the codec error.
 func (ctx *Context) Decode(dest interface{}) error {
-	return ctx.Codec.Decode(ctx.Request.Body, dest)
+	codec, err := ctx.Decoder()
+	if err != nil {
+		return err
+	}
+	if err = codec.Decode(ctx.Request.Body, dest); err != nil {
+		var herr *HTTPError
+		if errors.As(err, &herr) {
+			return err
+		}
+		return &HTTPError{Status: http.StatusBadRequest, Code: "invalid_body", Message: "malformed request body", Err: err}
+	}
+	return nil
 }
 
-// Encode encodes the given data to the context response
+// Encode encodes the given data to the context response, using the Codec negotiated
+// from the Accept request header (see Encoder)
 func (ctx *Context) Encode(data interface{}) error {
-	return ctx.Codec.Encode(ctx, data)
+	return ctx.encode(ctx, data)
+}
+
+// EncodeStatus encodes the given data to the context response, sending the given status code.
+// The status is sent right before the first write, so the codec can still set the response headers.
+func (ctx *Context) EncodeStatus(status int, data interface{}) error {
+	return ctx.encode(&statusWriter{ResponseWriter: ctx.ResponseWriter, status: status}, data)
+}
+
+// encode encodes the data with the negotiated Codec, setting the Content-Type header
+// unless it is already set
+func (ctx *Context) encode(w http.ResponseWriter, data interface{}) error {
+	mediaType, codec, err := ctx.Encoder()
+	if err != nil {
+		return err
+	}
+	if mediaType != "" {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", mediaType)
+		}
+		addVary(w.Header(), "Accept")
+	}
+	return codec.Encode(w, data)
+}
+
+// addVary adds a header name to the Vary response header, unless it is already there
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
+
+// statusWriter delays sending the status code until the first write
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	sent   bool
+}
+
+// Write sends the status code, if not sent yet, and writes the data
+func (w *statusWriter) Write(data []byte) (int, error) {
+	if !w.sent {
+		w.sent = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	return w.ResponseWriter.Write(data)
 }
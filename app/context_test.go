@@ -0,0 +1,183 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/database"
+	"github.com/syb-devs/goth/encoding"
+	"github.com/syb-devs/goth/log"
+)
+
+type ctxKey string
+
+func TestContextCancellation(t *testing.T) {
+	a := app.NewApp("test")
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/", nil).WithContext(reqCtx)
+	ctx := a.NewContextHTTP(httptest.NewRecorder(), req)
+
+	ctx.WithValue(ctxKey("user"), "john")
+	release := ctx.WithTimeout(time.Hour)
+	defer release()
+
+	if v := ctx.Context().Value(ctxKey("user")); v != "john" {
+		t.Errorf("expecting context value john, got %v", v)
+	}
+	if _, ok := ctx.Context().Deadline(); !ok {
+		t.Error("expecting the context to have a deadline")
+	}
+
+	cancel()
+	select {
+	case <-ctx.Context().Done():
+	case <-time.After(time.Second):
+		t.Error("expecting the context to be cancelled with the request")
+	}
+}
+
+type fakeConn struct {
+	copies *int
+	closed *int
+}
+
+func (c *fakeConn) Connect(database.ConnectionParams) error { return nil }
+func (c *fakeConn) Close() error                            { *c.closed++; return nil }
+func (c *fakeConn) Interface() interface{}                  { return nil }
+func (c *fakeConn) Copy() database.Connection {
+	*c.copies++
+	return &fakeConn{copies: c.copies, closed: c.closed}
+}
+
+type fakeRepo struct {
+	database.Repository
+	conn database.Connection
+}
+
+func TestContextDBSession(t *testing.T) {
+	var copies, closed int
+	a := app.NewApp("test")
+	a.DB.Connection = &fakeConn{copies: &copies, closed: &closed}
+	a.DB.NewRepository = func(conn database.Connection) database.Repository {
+		return &fakeRepo{conn: conn}
+	}
+
+	var reqConn database.Connection
+	h := app.HandlerFunc(func(ctx *app.Context) error {
+		reqConn = ctx.Conn
+		if repo, ok := ctx.DB.(*fakeRepo); !ok || repo.conn != ctx.Conn {
+			t.Errorf("expecting the request repository to be bound to the request connection")
+		}
+		return errors.New("handler failed")
+	})
+	a.Log = log.NilLogger{}
+	ctx := a.NewContextHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	app.Dispatch(ctx, h)
+
+	if reqConn == a.DB.Connection {
+		t.Error("expecting the request connection to be a copy of the App one")
+	}
+	if copies != 1 || closed != 1 {
+		t.Errorf("expecting one copy and one close, got %d copies and %d closes", copies, closed)
+	}
+}
+
+// textCodec decodes the request body as a string, and encodes data with fmt.Fprint
+type textCodec struct{}
+
+func (textCodec) Encode(w io.Writer, data interface{}) error {
+	_, err := fmt.Fprint(w, data)
+	return err
+}
+
+func (textCodec) Decode(r io.Reader, dest interface{}) error {
+	body, err := io.ReadAll(r)
+	*dest.(*interface{}) = string(body)
+	return err
+}
+
+func init() {
+	encoding.Register("text/plain", textCodec{})
+}
+
+func TestContentNegotiation(t *testing.T) {
+	tests := []struct {
+		contentType string
+		accept      string
+		body        string
+		status      int
+		resType     string
+		resBody     string
+	}{
+		{"", "", `"hi"`, http.StatusOK, "application/json", "\"hi\"\n"},
+		{"application/json; charset=utf-8", "*/*", `"hi"`, http.StatusOK, "application/json", "\"hi\"\n"},
+		{"text/plain", "text/html, text/*;q=0.9", "hi", http.StatusOK, "text/plain", "hi"},
+		{"text/plain", "application/json;q=0.5, text/plain", "hi", http.StatusOK, "text/plain", "hi"},
+		{"text/plain", "application/*", "hi", http.StatusOK, "application/json", "\"hi\"\n"},
+		{"application/yaml", "", "hi: there", http.StatusUnsupportedMediaType, app.ProblemContentType, ""},
+		{"text/plain", "text/html", "hi", http.StatusNotAcceptable, app.ProblemContentType, ""},
+		{"text/plain", "text/plain, application/json;q=0", "hi", http.StatusOK, "text/plain", "hi"},
+		{"application/json", "", `{"hi"`, http.StatusBadRequest, app.ProblemContentType, ""},
+	}
+
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	h := app.HandlerFunc(func(ctx *app.Context) error {
+		var data interface{}
+		if err := ctx.Decode(&data); err != nil {
+			return err
+		}
+		return ctx.Encode(data)
+	})
+
+	for i, test := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		rec := httptest.NewRecorder()
+		app.Dispatch(a.NewContextHTTP(rec, req), h)
+
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != test.resType {
+			t.Errorf("test #%d: expecting content type %s, got %s", i+1, test.resType, ct)
+		}
+		if test.resBody != "" && rec.Body.String() != test.resBody {
+			t.Errorf("test #%d: expecting body %q, got %q", i+1, test.resBody, rec.Body.String())
+		}
+		if test.status == http.StatusOK && rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("test #%d: expecting Vary header %q, got %q", i+1, "Accept", rec.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestContextCodec(t *testing.T) {
+	a := app.NewApp("test")
+	req := httptest.NewRequest("POST", "/", strings.NewReader("hi"))
+	req.Header.Set("Content-Type", "application/yaml")
+	req.Header.Set("Accept", "application/yaml")
+	rec := httptest.NewRecorder()
+	ctx := a.NewContextHTTP(rec, req)
+	ctx.Codec = textCodec{}
+
+	var data interface{}
+	if err := ctx.Decode(&data); err != nil {
+		t.Fatalf("unexpected error decoding with the Context codec: %v", err)
+	}
+	if err := ctx.Encode(data); err != nil || rec.Body.String() != "hi" {
+		t.Errorf("expecting body %q, got %q (error %v)", "hi", rec.Body.String(), err)
+	}
+}
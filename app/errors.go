@@ -0,0 +1,212 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/syb-devs/goth/database"
+	"github.com/syb-devs/goth/encoding"
+	"github.com/syb-devs/goth/encoding/json"
+	"github.com/syb-devs/goth/validate"
+)
+
+// ProblemContentType is the media type for the RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+var (
+	// ErrAccessDenied is returned when the user is not allowed to perform an action
+	ErrAccessDenied = &HTTPError{Status: http.StatusForbidden, Code: "access_denied", Message: "access denied"}
+	// ErrNotFound is returned when the requested resource does not exist
+	ErrNotFound = &HTTPError{Status: http.StatusNotFound, Code: "not_found", Message: "resource not found"}
+	// ErrMethodNotAllowed is returned when the requested path does not support the request method
+	ErrMethodNotAllowed = &HTTPError{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Message: "method not allowed"}
+	// ErrUnsupportedMediaType is returned when there is no codec for the Content-Type of the request
+	ErrUnsupportedMediaType = &HTTPError{Status: http.StatusUnsupportedMediaType, Code: "unsupported_media_type", Message: "unsupported media type"}
+	// ErrNotAcceptable is returned when there is no codec for any of the media types accepted by the client
+	ErrNotAcceptable = &HTTPError{Status: http.StatusNotAcceptable, Code: "not_acceptable", Message: "none of the accepted media types is supported"}
+)
+
+// HTTPError is an error that carries the information needed to build an HTTP error response
+type HTTPError struct {
+	// Status is the HTTP status code
+	Status int
+	// Code is an application specific, machine readable, error code
+	Code string
+	// Message is a human readable explanation of the error
+	Message string
+	// Details holds any extra information about the error (i.e. validation errors by field)
+	Details interface{}
+	// Err is the original error, if any
+	Err error
+}
+
+// NewHTTPError allocates and returns an HTTPError with the given status and code, wrapping err
+func NewHTTPError(status int, code string, err error) *HTTPError {
+	return &HTTPError{
+		Status: status,
+		Code:   code,
+		Err:    err,
+	}
+}
+
+// Error returns the error message
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return http.StatusText(e.Status)
+}
+
+// Unwrap returns the original error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Problem returns the RFC 7807 problem details representation of the error
+func (e *HTTPError) Problem(r *http.Request) *Problem {
+	p := &Problem{
+		Type:    "about:blank",
+		Title:   http.StatusText(e.Status),
+		Status:  e.Status,
+		Code:    e.Code,
+		Details: e.Details,
+	}
+	// Do not leak internal error messages to the clients
+	if e.Status < http.StatusInternalServerError {
+		p.Detail = e.Error()
+	}
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+	return p
+}
+
+// Problem represents an RFC 7807 problem details object
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// ErrorHandler is a function that writes the response for an error returned by a Handler
+type ErrorHandler func(ctx *Context, err error)
+
+// ErrorMapper is a function that converts known errors into HTTPErrors.
+// It returns nil for the errors it does not know about.
+type ErrorMapper func(err error) *HTTPError
+
+// MapError adds an ErrorMapper to the App. Mappers are tried in reverse order
+// of addition, before the built-in ones.
+func (a *App) MapError(m ErrorMapper) {
+	a.Lock()
+	defer a.Unlock()
+	a.errMappers = append([]ErrorMapper{m}, a.errMappers...)
+}
+
+// SetErrorHandler sets the function used to handle the errors returned by the App handlers
+func (a *App) SetErrorHandler(h ErrorHandler) {
+	a.Lock()
+	defer a.Unlock()
+	a.errHandler = h
+}
+
+// HandleError handles an error returned by a Handler, using the App ErrorHandler
+func (a *App) HandleError(ctx *Context, err error) {
+	if err == nil {
+		return
+	}
+	if a.errHandler != nil {
+		a.errHandler(ctx, err)
+		return
+	}
+	DefaultErrorHandler(ctx, err)
+}
+
+// ToHTTPError converts any error into an HTTPError, using the App error mappers.
+// Unknown errors are converted to internal server errors.
+func (a *App) ToHTTPError(err error) *HTTPError {
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		return herr
+	}
+	for _, mapper := range a.errMappers {
+		if herr = mapper(err); herr != nil {
+			return herr
+		}
+	}
+	return NewHTTPError(http.StatusInternalServerError, "internal_error", err)
+}
+
+// ProblemEncoder is implemented by the Codecs able to represent the Problem details objects,
+// returning the media type for them (i.e. application/problem+xml)
+type ProblemEncoder interface {
+	ProblemMediaType() string
+}
+
+// DefaultErrorHandler logs server errors and renders the error as an RFC 7807 problem details
+// object. The problem is encoded with the Codec negotiated for the response if it implements
+// ProblemEncoder, or as application/problem+json otherwise, i.e. for plain text codecs or if
+// no media type is acceptable. If the response has already been written, it only logs.
+func DefaultErrorHandler(ctx *Context, err error) {
+	herr := ctx.App.ToHTTPError(err)
+	if herr.Status >= http.StatusInternalServerError {
+		ctx.App.Log.Errorf("error serving %s %s: %v", ctx.Request.Method, ctx.Request.URL.String(), err)
+	} else {
+		ctx.App.Log.Debugf("error serving %s %s: %v", ctx.Request.Method, ctx.Request.URL.String(), err)
+	}
+	if ctx.Written() {
+		return
+	}
+	mediaType, codec := ProblemContentType, encoding.Codec(json.Codec{})
+	if _, c, err := ctx.Encoder(); err == nil {
+		if pe, ok := c.(ProblemEncoder); ok {
+			mediaType, codec = pe.ProblemMediaType(), c
+		}
+	}
+	ctx.Header().Set("Content-Type", mediaType)
+	ctx.WriteHeader(herr.Status)
+	if err := codec.Encode(ctx, herr.Problem(ctx.Request)); err != nil {
+		ctx.App.Log.Errorf("error encoding error response: %v", err)
+	}
+}
+
+func defaultErrorMappers() []ErrorMapper {
+	return []ErrorMapper{
+		mapNotFound,
+		mapValidation,
+	}
+}
+
+func mapNotFound(err error) *HTTPError {
+	if errors.Is(err, database.ErrNotFound) {
+		return &HTTPError{Status: http.StatusNotFound, Code: ErrNotFound.Code, Message: ErrNotFound.Message, Err: err}
+	}
+	return nil
+}
+
+func mapValidation(err error) *HTTPError {
+	var verr *validate.ValidationError
+	if !errors.As(err, &verr) {
+		return nil
+	}
+	details := make(map[string][]string, len(verr.FieldErrors))
+	for field, errs := range verr.FieldErrors {
+		for _, ferr := range errs {
+			details[field] = append(details[field], ferr.Error())
+		}
+	}
+	return &HTTPError{
+		Status:  http.StatusUnprocessableEntity,
+		Code:    "validation_failed",
+		Message: "validation failed",
+		Details: details,
+		Err:     err,
+	}
+}
@@ -0,0 +1,96 @@
+package app_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/database"
+	"github.com/syb-devs/goth/log"
+	"github.com/syb-devs/goth/validate"
+)
+
+func TestHandleError(t *testing.T) {
+	errTeapot := errors.New("short and stout")
+	tests := []struct {
+		err     error
+		status  int
+		code    string
+		detail  string
+		details bool
+	}{
+		{fmt.Errorf("fetching todo: %w", database.ErrNotFound), http.StatusNotFound, "not_found", "resource not found", false},
+		{app.ErrAccessDenied, http.StatusForbidden, "access_denied", "access denied", false},
+		{&validate.ValidationError{FieldErrors: validate.FieldErrors{"Name": {errors.New("required")}}}, http.StatusUnprocessableEntity, "validation_failed", "validation failed", true},
+		{app.NewHTTPError(http.StatusBadRequest, "bad_input", errors.New("invalid JSON")), http.StatusBadRequest, "bad_input", "invalid JSON", false},
+		{errors.New("database exploded"), http.StatusInternalServerError, "internal_error", "", false},
+		{errTeapot, http.StatusTeapot, "teapot", "short and stout", false},
+	}
+
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	a.MapError(func(err error) *app.HTTPError {
+		if err == errTeapot {
+			return app.NewHTTPError(http.StatusTeapot, "teapot", err)
+		}
+		return nil
+	})
+
+	for i, test := range tests {
+		rec := httptest.NewRecorder()
+		ctx := a.NewContextHTTP(rec, httptest.NewRequest("GET", "/todos/1", nil))
+		a.HandleError(ctx, test.err)
+
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != app.ProblemContentType {
+			t.Errorf("test #%d: expecting content type %s, got %s", i+1, app.ProblemContentType, ct)
+		}
+		var p app.Problem
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatalf("test #%d: error decoding problem: %v", i+1, err)
+		}
+		if p.Status != test.status || p.Code != test.code || p.Detail != test.detail || p.Instance != "/todos/1" {
+			t.Errorf("test #%d: unexpected problem %+v", i+1, p)
+		}
+		if test.details != (p.Details != nil) {
+			t.Errorf("test #%d: unexpected problem details %+v", i+1, p.Details)
+		}
+	}
+}
+
+func TestHandleErrorPlainText(t *testing.T) {
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/todos/1", nil)
+	req.Header.Set("Accept", "text/plain")
+	a.HandleError(a.NewContextHTTP(rec, req), app.ErrNotFound)
+
+	if ct := rec.Header().Get("Content-Type"); ct != app.ProblemContentType {
+		t.Errorf("expecting content type %s, got %s", app.ProblemContentType, ct)
+	}
+	var p app.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil || p.Status != http.StatusNotFound {
+		t.Errorf("expecting a JSON problem, got %+v (%v)", p, err)
+	}
+}
+
+func TestHandleErrorWritten(t *testing.T) {
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+
+	rec := httptest.NewRecorder()
+	ctx := a.NewContextHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	ctx.WriteHeader(http.StatusAccepted)
+	a.HandleError(ctx, errors.New("too late"))
+
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Errorf("expecting the written response to be kept, got %d %q", rec.Code, rec.Body.String())
+	}
+}
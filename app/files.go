@@ -0,0 +1,161 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultMaxFileSize is the default maximum size in bytes of each uploaded file
+	DefaultMaxFileSize = 10 << 20
+	// DefaultMaxUploadSize is the default maximum size in bytes of a multipart request body
+	DefaultMaxUploadSize = 32 << 20
+)
+
+var (
+	// ErrNotMultipart is returned by Files when the request is not a multipart form
+	ErrNotMultipart = &HTTPError{Status: http.StatusUnsupportedMediaType, Code: "not_multipart", Message: "request must be a multipart/form-data form"}
+	// ErrUploadTooLarge is returned by Files when the request body, a file or the number of files exceed the limits
+	ErrUploadTooLarge = &HTTPError{Status: http.StatusRequestEntityTooLarge, Code: "upload_too_large", Message: "upload exceeds the size limits"}
+	// ErrFileType is returned by Files when a file type is not allowed
+	ErrFileType = &HTTPError{Status: http.StatusUnsupportedMediaType, Code: "file_type_not_allowed", Message: "file type not allowed"}
+)
+
+// FileLimits are the limits checked by Context.Files
+type FileLimits struct {
+	// MaxFileSize is the maximum size in bytes of each file. Defaults to DefaultMaxFileSize.
+	MaxFileSize int64
+	// MaxUploadSize is the maximum size in bytes of the request body. Defaults to DefaultMaxUploadSize.
+	// If the form was parsed before (i.e. by Bind), the MaxFormSize limit applied instead.
+	MaxUploadSize int64
+	// MaxFiles is the maximum number of files. Zero means no limit.
+	MaxFiles int
+	// AllowedTypes are the media types allowed, which can be ranges like "image/*". The type of
+	// the files is detected from their content, not trusting the one sent by the client.
+	// Empty means any type.
+	AllowedTypes []string
+}
+
+// File is a file uploaded in a multipart form
+type File struct {
+	// Field is the name of the form field
+	Field string
+	// Filename is the name of the file sent by the client, without any directory
+	Filename string
+	// Size is the size of the file in bytes
+	Size int64
+	// ContentType is the media type detected from the file content
+	ContentType string
+
+	header *multipart.FileHeader
+}
+
+// Open opens the file for reading
+func (f *File) Open() (multipart.File, error) {
+	return f.header.Open()
+}
+
+// Files are the uploaded files of a request, by form field
+type Files map[string][]*File
+
+// First returns the first file uploaded for the form field, or nil if none
+func (fs Files) First(field string) *File {
+	if files := fs[field]; len(files) > 0 {
+		return files[0]
+	}
+	return nil
+}
+
+// Files parses the multipart form of the request, returning its files once checked against the limits.
+// Files over MaxFormMemory are stored in temporary files, which are removed once the request is served.
+// A malformed form is returned as a 400 Bad Request HTTPError, and the files exceeding the limits as
+// ErrUploadTooLarge or ErrFileType.
+func (ctx *Context) Files(limits ...FileLimits) (Files, error) {
+	var l FileLimits
+	if len(limits) > 0 {
+		l = limits[0]
+	}
+	if l.MaxFileSize == 0 {
+		l.MaxFileSize = DefaultMaxFileSize
+	}
+	if l.MaxUploadSize == 0 {
+		l.MaxUploadSize = DefaultMaxUploadSize
+	}
+
+	r := ctx.Request
+	if r.MultipartForm == nil {
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+			return nil, ErrNotMultipart
+		}
+		r.Body = http.MaxBytesReader(ctx.ResponseWriter, r.Body, l.MaxUploadSize)
+		if err := r.ParseMultipartForm(MaxFormMemory); err != nil {
+			if isTooLarge(err) {
+				return nil, ErrUploadTooLarge
+			}
+			return nil, &HTTPError{Status: http.StatusBadRequest, Code: "invalid_body", Message: "malformed multipart form", Err: err}
+		}
+	}
+
+	files := make(Files)
+	count := 0
+	for field, headers := range r.MultipartForm.File {
+		for _, fh := range headers {
+			count++
+			if l.MaxFiles > 0 && count > l.MaxFiles {
+				return nil, ErrUploadTooLarge
+			}
+			if fh.Size > l.MaxFileSize {
+				return nil, ErrUploadTooLarge
+			}
+			ctype, err := detectType(fh)
+			if err != nil {
+				return nil, err
+			}
+			if !typeAllowed(ctype, l.AllowedTypes) {
+				return nil, ErrFileType
+			}
+			files[field] = append(files[field], &File{
+				Field:       field,
+				Filename:    fh.Filename,
+				Size:        fh.Size,
+				ContentType: ctype,
+				header:      fh,
+			})
+		}
+	}
+	return files, nil
+}
+
+// detectType detects the media type of an uploaded file from its first bytes
+func detectType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var buf [512]byte
+	n, err := io.ReadFull(f, buf[:])
+	if err != nil && err != io.EOF && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return mediaType, nil
+}
+
+// typeAllowed checks if the media type matches any of the allowed ones, which can be ranges like "image/*"
+func typeAllowed(mediaType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == mediaType || a == "*/*" || strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
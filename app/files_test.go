@@ -0,0 +1,154 @@
+package app_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/log"
+)
+
+var pngData = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// multipartBody builds a multipart form with a title field and the given files, by field name
+func multipartBody(t *testing.T, files map[string][]byte) (*bytes.Buffer, string) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.WriteField("title", "holidays")
+	for field, data := range files {
+		fw, err := w.CreateFormFile(strings.TrimRight(field, "0123456789"), "dir/"+field+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(data)
+	}
+	w.Close()
+	return &buf, w.FormDataContentType()
+}
+
+func TestFiles(t *testing.T) {
+	tests := []struct {
+		files  map[string][]byte
+		limits app.FileLimits
+		status int
+	}{
+		{map[string][]byte{"photo": pngData, "notes": []byte("some notes")}, app.FileLimits{}, http.StatusOK},
+		{map[string][]byte{"photo": pngData}, app.FileLimits{AllowedTypes: []string{"image/*"}}, http.StatusOK},
+		{map[string][]byte{"photo": pngData, "notes": []byte("some notes")}, app.FileLimits{AllowedTypes: []string{"image/png"}}, http.StatusUnsupportedMediaType},
+		{map[string][]byte{"photo": pngData}, app.FileLimits{MaxFileSize: 8}, http.StatusRequestEntityTooLarge},
+		{map[string][]byte{"photo": pngData}, app.FileLimits{MaxUploadSize: 64}, http.StatusRequestEntityTooLarge},
+		{map[string][]byte{"photo1": pngData, "photo2": pngData}, app.FileLimits{MaxFiles: 1}, http.StatusRequestEntityTooLarge},
+		{map[string][]byte{"photo1": pngData, "photo2": pngData}, app.FileLimits{MaxFiles: 2}, http.StatusOK},
+	}
+
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	for i, test := range tests {
+		var files app.Files
+		h := app.HandlerFunc(func(ctx *app.Context) error {
+			var err error
+			files, err = ctx.Files(test.limits)
+			return err
+		})
+		body, contentType := multipartBody(t, test.files)
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		app.Dispatch(a.NewContextHTTP(rec, req), h)
+
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, rec.Code)
+			continue
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		count := 0
+		for _, ff := range files {
+			count += len(ff)
+		}
+		if count != len(test.files) {
+			t.Errorf("test #%d: expecting %d files, got %d", i+1, len(test.files), count)
+		}
+	}
+}
+
+func TestFilesContent(t *testing.T) {
+	a := app.NewApp("test")
+	body, contentType := multipartBody(t, map[string][]byte{"photo": pngData})
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	ctx := a.NewContextHTTP(httptest.NewRecorder(), req)
+
+	files, err := ctx.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := files.First("photo")
+	if f == nil || f.Filename != "photo.bin" || f.ContentType != "image/png" || f.Size != int64(len(pngData)) {
+		t.Fatalf("expecting the photo upload, got %+v", f)
+	}
+	r, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if data, _ := io.ReadAll(r); !bytes.Equal(data, pngData) {
+		t.Errorf("expecting the file content %q, got %q", pngData, data)
+	}
+	if files.First("missing") != nil {
+		t.Error("expecting no file for a missing field")
+	}
+	if ctx.Request.FormValue("title") != "holidays" {
+		t.Errorf("expecting the form values to be parsed too, got %q", ctx.Request.FormValue("title"))
+	}
+
+	ctx = a.NewContextHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/upload", strings.NewReader("{}")))
+	if _, err := ctx.Files(); err != app.ErrNotMultipart {
+		t.Errorf("expecting error %v, got %v", app.ErrNotMultipart, err)
+	}
+}
+
+func TestFilesRemoved(t *testing.T) {
+	defer func(size int64) { app.MaxFormMemory = size }(app.MaxFormMemory)
+	app.MaxFormMemory = 1
+	t.Setenv("TMPDIR", t.TempDir())
+
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	var name string
+	h := app.HandlerFunc(func(ctx *app.Context) error {
+		// Handlers can replace the request with a shallow copy
+		ctx.WithValue("key", "value")
+		files, err := ctx.Files()
+		if err != nil {
+			return err
+		}
+		f, err := files.First("photo").Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if osFile, ok := f.(*os.File); ok {
+			name = osFile.Name()
+		}
+		return nil
+	})
+	body, contentType := multipartBody(t, map[string][]byte{"photo": pngData})
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	app.Dispatch(a.NewContextHTTP(httptest.NewRecorder(), req), h)
+
+	if name == "" {
+		t.Fatal("expecting the upload to be stored in a temporary file")
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("expecting the temporary file to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"strings"
+)
+
+// Group is a set of routes sharing a path prefix and a middleware chain.
+// Groups can be nested, inheriting the prefix, chain and middlewares of their parent.
+type Group struct {
+	app       *App
+	host      *VHost
+	prefix    string
+	chainName string
+	mws       []Middleware
+}
+
+// Group returns a route group for the given path prefix, whose handlers will be wrapped
+// with the MiddlewareChain registered with the given name
+func (a *App) Group(prefix, chainName string) *Group {
+	return &Group{
+		app:       a,
+		prefix:    joinPath("", prefix),
+		chainName: chainName,
+	}
+}
+
+// Prefix returns the path prefix of the group
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+// Host returns the virtual host the group belongs to, or nil for the App default host
+func (g *Group) Host() *VHost {
+	return g.host
+}
+
+// App returns the App the group belongs to
+func (g *Group) App() *App {
+	return g.app
+}
+
+// Group returns a nested group with the given prefix, appended to the parent one
+func (g *Group) Group(prefix string) *Group {
+	return &Group{
+		app:       g.app,
+		host:      g.host,
+		prefix:    joinPath(g.prefix, prefix),
+		chainName: g.chainName,
+		mws:       append([]Middleware(nil), g.mws...),
+	}
+}
+
+// Use adds middlewares to the group, which will be run after the group chain
+// for the routes registered from now on, including those of nested groups created afterwards
+func (g *Group) Use(mws ...Middleware) *Group {
+	g.mws = append(g.mws, mws...)
+	return g
+}
+
+// Handle registers a handler for a given method / path combination, relative to the group prefix.
+// The given middlewares only apply to this route, running after the group ones.
+func (g *Group) Handle(method, path string, h Handler, mws ...Middleware) *Route {
+	full := joinPath(g.prefix, path)
+	if full == "" {
+		full = "/"
+	}
+	return g.app.handle(g.host, method, full, g.chainName, h, append(append([]Middleware(nil), g.mws...), mws...))
+}
+
+// HandleFunc registers a handler function for a given method / path combination, relative to the group prefix
+func (g *Group) HandleFunc(method, path string, h HandlerFunc, mws ...Middleware) *Route {
+	return g.Handle(method, path, h, mws...)
+}
+
+// wrap wraps the handler with the given middlewares, so the first one runs first.
+// The group chain is applied by App.handle.
+func wrap(h Handler, mws []Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// joinPath joins a prefix and a path, making sure there is a single slash between them
+func joinPath(prefix, path string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if path == "" {
+		return prefix
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return prefix + path
+}
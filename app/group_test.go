@@ -0,0 +1,96 @@
+package app_test
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+)
+
+type route struct {
+	method, path string
+	h            app.Handler
+}
+
+type recordingMuxer struct {
+	routes []route
+}
+
+func (m *recordingMuxer) Handle(method, path string, h app.Handler) {
+	m.routes = append(m.routes, route{method, path, h})
+}
+
+func tracer(name string, trace *[]string) app.Middleware {
+	return func(h app.Handler) app.Handler {
+		return app.HandlerFunc(func(ctx *app.Context) error {
+			*trace = append(*trace, name)
+			return h.Serve(ctx)
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	var trace []string
+	a := app.NewApp("test")
+	mux := &recordingMuxer{}
+	a.SetMuxer(mux)
+	a.AddChain(chain.New(tracer("chain", &trace)), "main")
+
+	api := a.Group("/api/v1/", "main")
+	api.Use(tracer("api", &trace))
+	todos := api.Group("todos").Use(tracer("todos", &trace))
+	api.Use(tracer("late", &trace))
+
+	noop := func(ctx *app.Context) error { trace = append(trace, "handler"); return nil }
+	api.HandleFunc("GET", "/", noop)
+	todos.HandleFunc("GET", "/:id", noop)
+
+	expectedPaths := []string{"/api/v1/", "/api/v1/todos/:id"}
+	var paths []string
+	for _, r := range mux.routes {
+		paths = append(paths, r.path)
+	}
+	if !reflect.DeepEqual(expectedPaths, paths) {
+		t.Errorf("expecting paths %v, got %v", expectedPaths, paths)
+	}
+
+	ctx := a.NewContextHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/todos/1", nil))
+	mux.routes[1].h.Serve(ctx)
+	expectedTrace := []string{"chain", "api", "todos", "handler"}
+	if !reflect.DeepEqual(expectedTrace, trace) {
+		t.Errorf("expecting middleware trace %v, got %v", expectedTrace, trace)
+	}
+}
+
+func TestRouteMiddlewares(t *testing.T) {
+	var trace []string
+	a := app.NewApp("test")
+	mux := &recordingMuxer{}
+	a.SetMuxer(mux)
+	a.AddChain(chain.New(tracer("pub", &trace)), "pub")
+	pub, ok := a.Chain("pub")
+	if !ok {
+		t.Fatal("expecting the pub chain to be registered")
+	}
+	a.AddChain(pub.Append(tracer("auth", &trace)), "main")
+
+	noop := func(ctx *app.Context) error { trace = append(trace, "handler"); return nil }
+	a.Group("/api", "main").Use(tracer("group", &trace)).HandleFunc("GET", "/me", noop, tracer("route", &trace))
+	a.Group("/api", "pub").HandleFunc("GET", "/login", noop)
+	a.Handle("GET", "/raw", app.HandlerFunc(noop), tracer("route", &trace))
+
+	expected := [][]string{
+		{"pub", "auth", "group", "route", "handler"},
+		{"pub", "handler"},
+		{"route", "handler"},
+	}
+	for i, r := range mux.routes {
+		trace = nil
+		r.h.Serve(a.NewContextHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", r.path, nil)))
+		if !reflect.DeepEqual(expected[i], trace) {
+			t.Errorf("test #%d: expecting middleware trace %v, got %v", i+1, expected[i], trace)
+		}
+	}
+}
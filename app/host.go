@@ -0,0 +1,228 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DefaultHostParam is the name of the URL parameter capturing the labels matched
+// by an unnamed wildcard in a host pattern (i.e. *.example.com)
+const DefaultHostParam = "subdomain"
+
+// hostParamsKey is the request context key for the values captured from the Host header
+type hostParamsKey struct{}
+
+// VHost is a virtual host of the App, serving the requests whose Host header matches its pattern
+// with its own Muxer.
+//
+// Host patterns are made of dot separated labels, which can be static (api.example.com),
+// params matching a single label (:tenant.example.com), or a leading wildcard matching
+// one or more labels (*.example.com or *name.example.com). The captured values are
+// available in Context.URLParams, along with the path params.
+type VHost struct {
+	app     *App
+	pattern string
+	labels  []string
+	muxer   Muxer
+	handler http.Handler
+}
+
+// Host registers a virtual host for the given host pattern, whose routes are bound to the given Muxer.
+// The Muxer must implement http.Handler. It panics if the pattern is already registered or malformed.
+func (a *App) Host(pattern string, m Muxer) *VHost {
+	handler, ok := m.(http.Handler)
+	if !ok {
+		panic(fmt.Sprintf("the Muxer for host %s must implement http.Handler", pattern))
+	}
+	vh := &VHost{
+		app:     a,
+		pattern: strings.ToLower(pattern),
+		muxer:   m,
+		handler: handler,
+	}
+	vh.labels = strings.Split(vh.pattern, ".")
+	for i, label := range vh.labels {
+		if label == "" || (label[0] == '*' && i > 0) {
+			panic(fmt.Sprintf("malformed host pattern %s", pattern))
+		}
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	for _, h := range a.hosts {
+		if h.pattern == vh.pattern {
+			panic(fmt.Sprintf("host %s already registered", pattern))
+		}
+	}
+	a.hosts = append(a.hosts, vh)
+	sort.SliceStable(a.hosts, func(i, j int) bool {
+		return a.hosts[i].precedes(a.hosts[j])
+	})
+	a.updateDispatch()
+	return vh
+}
+
+// Pattern returns the host pattern of the virtual host
+func (vh *VHost) Pattern() string {
+	return vh.pattern
+}
+
+// SetHandler sets the handler serving the requests of the virtual host, which defaults to its Muxer.
+// It allows wrapping the Muxer with net/http middlewares.
+func (vh *VHost) SetHandler(h http.Handler) {
+	vh.app.Lock()
+	defer vh.app.Unlock()
+	vh.handler = h
+	vh.app.updateDispatch()
+}
+
+// Group returns a route group of the virtual host for the given path prefix, whose handlers
+// will be wrapped with the MiddlewareChain registered with the given name
+func (vh *VHost) Group(prefix, chainName string) *Group {
+	g := vh.app.Group(prefix, chainName)
+	g.host = vh
+	return g
+}
+
+// Handle registers a handler for a given method / path combination in the virtual host,
+// wrapped with the given middlewares
+func (vh *VHost) Handle(method, path string, h Handler, mws ...Middleware) *Route {
+	return vh.app.handle(vh, method, path, "", h, mws)
+}
+
+// precedes reports whether the host should be tried before the other one: static patterns go first,
+// and then the patterns with more labels, as they are more specific
+func (vh *VHost) precedes(other *VHost) bool {
+	static, otherStatic := vh.static(), other.static()
+	if static != otherStatic {
+		return static
+	}
+	return len(vh.labels) > len(other.labels)
+}
+
+func (vh *VHost) static() bool {
+	return !strings.ContainsAny(vh.pattern, ":*")
+}
+
+// match checks if the given host name matches the host pattern, returning the captured values
+func (vh *VHost) match(host string) (URLParams, bool) {
+	labels := strings.Split(host, ".")
+	pattern := vh.labels
+	var params URLParams
+	capture := func(name, value string) {
+		if params == nil {
+			params = make(URLParams)
+		}
+		params[name] = value
+	}
+
+	if wildcard := pattern[0]; wildcard[0] == '*' {
+		n := len(labels) - len(pattern) + 1
+		if n < 1 {
+			return nil, false
+		}
+		name := wildcard[1:]
+		if name == "" {
+			name = DefaultHostParam
+		}
+		capture(name, strings.Join(labels[:n], "."))
+		labels, pattern = labels[n:], pattern[1:]
+	}
+	if len(labels) != len(pattern) {
+		return nil, false
+	}
+	for i, label := range pattern {
+		switch {
+		case label[0] == ':':
+			capture(label[1:], labels[i])
+		case label != labels[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// dispatchTable is a snapshot of the virtual hosts and handlers serving the requests. It is
+// rebuilt whenever they change, so ServeHTTP can read it without taking the App lock.
+type dispatchTable struct {
+	hosts    []*VHost
+	handlers []http.Handler
+	handler  http.Handler
+}
+
+// updateDispatch rebuilds the dispatch table. The App lock must be held.
+func (a *App) updateDispatch() {
+	t := &dispatchTable{
+		hosts:    append([]*VHost(nil), a.hosts...),
+		handlers: make([]http.Handler, len(a.hosts)),
+		handler:  a.handler,
+	}
+	for i, vh := range t.hosts {
+		t.handlers[i] = vh.handler
+	}
+	if t.handler == nil {
+		t.handler, _ = a.muxer.(http.Handler)
+	}
+	a.dispatch.Store(t)
+}
+
+// match returns the handler of the virtual host matching the given Host header, and the
+// captured values, or the App handler if no virtual host matches
+func (t *dispatchTable) match(host string) (http.Handler, URLParams) {
+	if len(t.hosts) > 0 {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		for i, vh := range t.hosts {
+			if params, ok := vh.match(host); ok {
+				return t.handlers[i], params
+			}
+		}
+	}
+	return t.handler, nil
+}
+
+// ServeHTTP dispatches the request to the virtual host matching its Host header, if any,
+// or to the App handler otherwise. If no handler is set, the App Muxer is used.
+func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t, _ := a.dispatch.Load().(*dispatchTable)
+	if t == nil {
+		http.NotFound(w, r)
+		return
+	}
+	handler, params := t.match(r.Host)
+	if handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if len(params) > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), hostParamsKey{}, params))
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// withHostParams adds the values captured from the Host header of the request to the
+// given URL params. Path params take precedence over host params with the same name.
+func withHostParams(r *http.Request, params URLParams) URLParams {
+	if r == nil {
+		return params
+	}
+	hostParams, ok := r.Context().Value(hostParamsKey{}).(URLParams)
+	if !ok {
+		return params
+	}
+	if params == nil {
+		params = make(URLParams, len(hostParams))
+	}
+	for name, value := range hostParams {
+		if _, exists := params[name]; !exists {
+			params[name] = value
+		}
+	}
+	return params
+}
@@ -0,0 +1,95 @@
+package app_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+	"github.com/syb-devs/goth/log"
+)
+
+func TestHosts(t *testing.T) {
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	a.SetMuxer(stdmux.New(a.NewContextHTTP))
+	a.AddChain(chain.New(), "main")
+
+	echo := func(name string) app.HandlerFunc {
+		return func(ctx *app.Context) error {
+			ctx.Header().Set("X-Host", name)
+			return ctx.Encode(ctx.URLParams)
+		}
+	}
+	a.Group("", "main").HandleFunc("GET", "/info", echo("default"))
+	a.Host("api.example.com", stdmux.New(a.NewContextHTTP)).Group("/v1", "main").HandleFunc("GET", "/info", echo("api"))
+	a.Host(":tenant.example.com", stdmux.New(a.NewContextHTTP)).Group("", "main").HandleFunc("GET", "/info/:id", echo("tenant"))
+	a.Host("*.users.example.com", stdmux.New(a.NewContextHTTP)).Group("", "main").HandleFunc("GET", "/info", echo("users"))
+	a.Host("*region.cdn.example.com", stdmux.New(a.NewContextHTTP)).Group("", "main").HandleFunc("GET", "/info", echo("cdn"))
+
+	tests := []struct {
+		host, path string
+		status     int
+		vhost      string
+		params     app.URLParams
+	}{
+		{"example.com", "/info", 200, "default", nil},
+		{"API.example.com:8080", "/v1/info", 200, "api", nil},
+		{"api.example.com", "/info", 404, "", nil},
+		{"acme.example.com", "/info/7", 200, "tenant", app.URLParams{"tenant": "acme", "id": "7"}},
+		{"john.users.example.com", "/info", 200, "users", app.URLParams{"subdomain": "john"}},
+		{"a.b.users.example.com", "/info", 200, "users", app.URLParams{"subdomain": "a.b"}},
+		{"users.example.com", "/info/1", 200, "tenant", app.URLParams{"tenant": "users", "id": "1"}},
+		{"eu.cdn.example.com", "/info", 200, "cdn", app.URLParams{"region": "eu"}},
+		{"other.org", "/missing", 404, "", nil},
+	}
+	for i, test := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", test.path, nil)
+		req.Host = test.host
+		a.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, rec.Code)
+			continue
+		}
+		if test.status != 200 {
+			continue
+		}
+		if vhost := rec.Header().Get("X-Host"); vhost != test.vhost {
+			t.Errorf("test #%d: expecting host %s, got %s", i+1, test.vhost, vhost)
+		}
+		var params app.URLParams
+		if err := json.NewDecoder(rec.Body).Decode(&params); err != nil {
+			t.Fatal(err)
+		}
+		if len(params) != 0 || len(test.params) != 0 {
+			if !reflect.DeepEqual(test.params, params) {
+				t.Errorf("test #%d: expecting params %v, got %v", i+1, test.params, params)
+			}
+		}
+	}
+
+	routes := a.Routes()
+	if routes[0].Host != "" || routes[1].Host != "*.users.example.com" {
+		t.Errorf("expecting routes sorted by host, got %+v", routes)
+	}
+}
+
+func TestHostPanics(t *testing.T) {
+	tests := []string{"api..example.com", "api.*.example.com", "dup.example.com"}
+	for i, pattern := range tests {
+		a := app.NewApp("test")
+		a.Host("dup.example.com", stdmux.New(nil))
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("test #%d: expecting panic registering host %s", i+1, pattern)
+				}
+			}()
+			a.Host(pattern, stdmux.New(nil))
+		}()
+	}
+}
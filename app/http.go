@@ -6,6 +6,18 @@ type Handler interface {
 	Serve(*Context) error
 }
 
+// Dispatch serves a request with the given Handler and Context, passing any returned
+// error to the App error handler, and closes the Context afterwards.
+// The values captured from the Host header by the App virtual hosts are added to the URL params.
+// Muxer implementations use it to run the handlers bound to their routes.
+func Dispatch(ctx *Context, h Handler) {
+	defer ctx.Close()
+	ctx.URLParams = withHostParams(ctx.Request, ctx.URLParams)
+	if err := h.Serve(ctx); err != nil {
+		ctx.App.HandleError(ctx, err)
+	}
+}
+
 // HandlerFunc represents a function that will serve an HTTP request
 type HandlerFunc func(*Context) error
 
@@ -22,17 +34,43 @@ func (p URLParams) ByName(name string) string {
 	return p[name]
 }
 
-// Muxer interface is used to bind a handler to a route
+// Muxer interface is used to bind a handler to a route. The catch-all segments of the paths
+// (i.e. /static/*path) must also match the empty rest of the path, after the trailing slash.
 type Muxer interface {
 	Handle(verb, path string, h Handler)
 }
 
+// FallbackMuxer is implemented by Muxers which serve the requests matching a registered path,
+// but none of its methods, with a fallback handler bound to the path. HEAD requests should be
+// served by the GET handler of the path, if any, before falling back.
+// The App registers a fallback answering OPTIONS requests and 405 Method Not Allowed errors,
+// wrapped with the middlewares and chain of the first route registered for the path, or the
+// DefaultChain if it has no chain.
+type FallbackMuxer interface {
+	Muxer
+	HandleFallback(path string, h Handler)
+}
+
 // Middleware is a function that wraps a handler and performs some tasks
 // before and/or after calling the wrapped handler
 type Middleware func(Handler) Handler
 
-// MiddlewareChain interface is satisfied by objects that can build middleware chains
+// MiddlewareChain interface is satisfied by objects that can build middleware chains.
+// Chains are immutable: the methods returning a MiddlewareChain return a new one.
 type MiddlewareChain interface {
+	// Append returns a chain with the given middlewares added after the ones of the chain
 	Append(...Middleware) MiddlewareChain
+	// Prepend returns a chain with the given middlewares added before the ones of the chain
+	Prepend(...Middleware) MiddlewareChain
+	// Extend returns a chain running the middlewares of the given chains after the ones of the chain
+	Extend(...MiddlewareChain) MiddlewareChain
+	// When returns a chain whose middlewares only apply to the requests satisfying the predicate
+	When(func(*Context) bool) MiddlewareChain
+	// SkipPaths returns a chain whose middlewares apply to every request, except those whose
+	// path is one of the given ones. Paths ending with /* skip everything below them.
+	SkipPaths(...string) MiddlewareChain
+	// Middlewares returns a copy of the middlewares of the chain
+	Middlewares() []Middleware
+	// Finally returns the given Handler wrapped inside of the chain middlewares
 	Finally(Handler) Handler
 }
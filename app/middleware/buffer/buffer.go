@@ -31,6 +31,12 @@ func (b *bufferedWriter) WriteHeader(statusCode int) {
 	b.statusCode = statusCode
 }
 
+// Reset discards the buffered data and status code
+func (b *bufferedWriter) Reset() {
+	b.statusCode = 0
+	b.buff = bytes.NewBuffer(nil)
+}
+
 // Release writes the data and status code in the ResponseWriter
 func (b *bufferedWriter) Release() (int64, error) {
 	if b.statusCode != 0 {
@@ -39,16 +45,25 @@ func (b *bufferedWriter) Release() (int64, error) {
 	return io.Copy(b.ResponseWriter, b.buff)
 }
 
-// New returns a middleware for buffered writing
+// New returns a middleware for buffered writing.
+// When the wrapped handler returns an error, the buffered response is discarded
+// so the App error handler can write the error response.
 func New() app.Middleware {
 	return func(h app.Handler) app.Handler {
 		return app.HandlerFunc(
 			func(ctx *app.Context) error {
-				buffw := newBufferedWriter(ctx.ResponseWriter)
-				defer buffw.Release()
-
+				w := ctx.ResponseWriter
+				buffw := newBufferedWriter(w)
 				ctx.ResponseWriter = buffw
-				return h.Serve(ctx)
+
+				err := h.Serve(ctx)
+				ctx.ResponseWriter = w
+				if err != nil {
+					buffw.Reset()
+					return err
+				}
+				_, err = buffw.Release()
+				return err
 			})
 	}
 }
@@ -1,10 +1,14 @@
 package chain
 
 import (
+	"strings"
+
 	"github.com/syb-devs/goth/app"
 )
 
-// Chain is an ordered collection of Middlewares
+// Chain is an ordered collection of Middlewares.
+// Chains are immutable: Append, Prepend and Extend return new chains, so a base
+// chain can be safely reused to build several variations of it.
 type Chain struct {
 	mws []app.Middleware
 }
@@ -12,13 +16,47 @@ type Chain struct {
 // New allocates a new Middleware Chain with the given Middlewares and returns it
 func New(mws ...app.Middleware) Chain {
 	return Chain{
-		mws: mws,
+		mws: append([]app.Middleware(nil), mws...),
+	}
+}
+
+// Append returns a new Chain with the given middlewares added after the ones of the Chain
+func (c Chain) Append(mws ...app.Middleware) app.MiddlewareChain {
+	return Chain{mws: append(c.Middlewares(), mws...)}
+}
+
+// Prepend returns a new Chain with the given middlewares added before the ones of the Chain
+func (c Chain) Prepend(mws ...app.Middleware) app.MiddlewareChain {
+	return Chain{mws: append(New(mws...).mws, c.mws...)}
+}
+
+// Extend returns a new Chain running the middlewares of the given chains after the ones of the Chain
+func (c Chain) Extend(chains ...app.MiddlewareChain) app.MiddlewareChain {
+	mws := c.Middlewares()
+	for _, other := range chains {
+		if oc, ok := other.(Chain); ok {
+			mws = append(mws, oc.mws...)
+		} else {
+			mws = append(mws, other.Finally)
+		}
 	}
+	return Chain{mws: mws}
+}
+
+// When returns a new Chain whose middlewares only apply to the requests satisfying the predicate
+func (c Chain) When(pred func(*app.Context) bool) app.MiddlewareChain {
+	return New(When(pred, c.Finally))
 }
 
-// Append adds another middleware to the Chain
-func (c Chain) Append(mw ...app.Middleware) app.MiddlewareChain {
-	return c
+// SkipPaths returns a new Chain whose middlewares apply to every request, except those whose
+// path is one of the given ones (see the SkipPaths function)
+func (c Chain) SkipPaths(paths ...string) app.MiddlewareChain {
+	return New(SkipPaths(c.Finally, paths...))
+}
+
+// Middlewares returns a copy of the middlewares of the Chain
+func (c Chain) Middlewares() []app.Middleware {
+	return append([]app.Middleware(nil), c.mws...)
 }
 
 // Finally returns the given Handler wrapped inside of the chain middlewares
@@ -33,3 +71,40 @@ func (c Chain) Finally(h app.Handler) app.Handler {
 	}
 	return h
 }
+
+// When returns a Middleware that only applies the given one to the requests satisfying the predicate.
+// The rest of the requests go straight to the next handler.
+func When(pred func(*app.Context) bool, mw app.Middleware) app.Middleware {
+	return func(next app.Handler) app.Handler {
+		wrapped := mw(next)
+		return app.HandlerFunc(func(ctx *app.Context) error {
+			if pred(ctx) {
+				return wrapped.Serve(ctx)
+			}
+			return next.Serve(ctx)
+		})
+	}
+}
+
+// SkipPaths returns a Middleware that applies the given one to every request, except those
+// whose path is one of the given ones. Paths ending with /* skip everything below them.
+func SkipPaths(mw app.Middleware, paths ...string) app.Middleware {
+	return When(func(ctx *app.Context) bool {
+		return !matchPath(ctx.Request.URL.Path, paths)
+	}, mw)
+}
+
+func matchPath(path string, paths []string) bool {
+	for _, p := range paths {
+		if prefix := strings.TrimSuffix(p, "*"); prefix != p {
+			if strings.HasPrefix(path, prefix) || path == strings.TrimSuffix(prefix, "/") {
+				return true
+			}
+			continue
+		}
+		if path == p {
+			return true
+		}
+	}
+	return false
+}
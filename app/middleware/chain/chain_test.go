@@ -0,0 +1,115 @@
+package chain_test
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+)
+
+func serve(c app.MiddlewareChain, path string) []string {
+	var trace []string
+	h := c.Finally(app.HandlerFunc(func(*app.Context) error {
+		trace = append(trace, "handler")
+		return nil
+	}))
+	a := app.NewApp("test")
+	ctx := a.NewContextHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	ctx.Store.Set("trace", &trace)
+	h.Serve(ctx)
+	return trace
+}
+
+func traced(name string) app.Middleware {
+	return func(h app.Handler) app.Handler {
+		return app.HandlerFunc(func(ctx *app.Context) error {
+			trace := ctx.Store.Get("trace").(*[]string)
+			*trace = append(*trace, name)
+			return h.Serve(ctx)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	base := chain.New(traced("a"), traced("b"))
+	other := chain.New(traced("x"))
+
+	tests := []struct {
+		chain    app.MiddlewareChain
+		expected []string
+	}{
+		{base, []string{"a", "b", "handler"}},
+		{base.Append(traced("c")), []string{"a", "b", "c", "handler"}},
+		{base.Prepend(traced("z")), []string{"z", "a", "b", "handler"}},
+		{base.Extend(other, chain.New(traced("y"))), []string{"a", "b", "x", "y", "handler"}},
+		{base.Append(traced("c")).Prepend(traced("z")), []string{"z", "a", "b", "c", "handler"}},
+		{chain.New(base.Append(traced("c")).Middlewares()...), []string{"a", "b", "c", "handler"}},
+		// The base chain is not modified by the derived ones
+		{base, []string{"a", "b", "handler"}},
+	}
+	for i, test := range tests {
+		if trace := serve(test.chain, "/"); !reflect.DeepEqual(test.expected, trace) {
+			t.Errorf("test #%d: expecting trace %v, got %v", i+1, test.expected, trace)
+		}
+	}
+}
+
+func TestAppendDoesNotShareBackingArray(t *testing.T) {
+	base := chain.New(traced("a"), traced("b"), traced("c"))
+	c1 := base.Append(traced("x"))
+	c2 := base.Append(traced("y"))
+	if trace := serve(c1, "/"); !reflect.DeepEqual([]string{"a", "b", "c", "x", "handler"}, trace) {
+		t.Errorf("expecting the first derived chain to be kept, got %v", trace)
+	}
+	if trace := serve(c2, "/"); !reflect.DeepEqual([]string{"a", "b", "c", "y", "handler"}, trace) {
+		t.Errorf("expecting the second derived chain to be kept, got %v", trace)
+	}
+}
+
+func TestConditional(t *testing.T) {
+	c := chain.New(
+		chain.When(func(ctx *app.Context) bool { return ctx.Request.URL.Query().Get("debug") != "" }, traced("debug")),
+		chain.SkipPaths(traced("auth"), "/login", "/public/*"),
+	)
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"/todos", []string{"auth", "handler"}},
+		{"/todos?debug=1", []string{"debug", "auth", "handler"}},
+		{"/login", []string{"handler"}},
+		{"/login/other", []string{"auth", "handler"}},
+		{"/public", []string{"handler"}},
+		{"/public/css/app.css", []string{"handler"}},
+		{"/publication", []string{"auth", "handler"}},
+	}
+	for i, test := range tests {
+		if trace := serve(c, test.path); !reflect.DeepEqual(test.expected, trace) {
+			t.Errorf("test #%d: expecting trace %v, got %v", i+1, test.expected, trace)
+		}
+	}
+}
+
+func TestConditionalChain(t *testing.T) {
+	var base app.MiddlewareChain = chain.New(traced("a"), traced("b"))
+	debug := func(ctx *app.Context) bool { return ctx.Request.URL.Query().Get("debug") != "" }
+
+	tests := []struct {
+		chain    app.MiddlewareChain
+		path     string
+		expected []string
+	}{
+		{base.When(debug), "/todos", []string{"handler"}},
+		{base.When(debug), "/todos?debug=1", []string{"a", "b", "handler"}},
+		{base.SkipPaths("/health/*").Prepend(traced("z")), "/health/ready", []string{"z", "handler"}},
+		{base.SkipPaths("/health/*").Prepend(traced("z")), "/todos", []string{"z", "a", "b", "handler"}},
+	}
+	for i, test := range tests {
+		if trace := serve(test.chain, test.path); !reflect.DeepEqual(test.expected, trace) {
+			t.Errorf("test #%d: expecting trace %v, got %v", i+1, test.expected, trace)
+		}
+	}
+}
@@ -171,7 +171,9 @@ func Default() *Cors {
 // as necessary.
 func (c *Cors) Handler(h app.Handler) app.Handler {
 	return app.HandlerFunc(func(ctx *app.Context) error {
-		if ctx.Request.Method == "OPTIONS" {
+		// OPTIONS requests which are not CORS preflights are let through, so they can be
+		// answered by the application (i.e. with the allowed methods of the resource)
+		if ctx.Request.Method == "OPTIONS" && ctx.Request.Header.Get("Access-Control-Request-Method") != "" {
 			c.logf("Handler: Preflight request")
 			c.handlePreflight(ctx, ctx.Request)
 			// Preflight requests are standalone and should stop the chain as some other
@@ -181,6 +183,7 @@ func (c *Cors) Handler(h app.Handler) app.Handler {
 			if c.optionPassthrough {
 				return h.Serve(ctx)
 			}
+			ctx.WriteHeader(http.StatusNoContent)
 		} else {
 			c.logf("Handler: Actual request")
 			c.handleActualRequest(ctx, ctx.Request)
@@ -32,8 +32,9 @@ type Options struct {
 	// It can be either a shared secret or a public key.
 	// Default value: nil
 	ValidationKeyGetter jwt.Keyfunc
-	// The function that will be called when there's an error validating the token
-	// Default value:
+	// The function that will be called when there's an error validating the token, writing the
+	// response itself
+	// Default value: nil, the error is returned as a 401 app.HTTPError, rendered by the App
 	ErrorHandler errorHandler
 	// A boolean indicating if the credentials are required or not
 	// Default value: false
@@ -61,6 +62,7 @@ type JWTMiddleware struct {
 	Options Options
 }
 
+// OnError is an ErrorHandler writing the error as a plain text 401 response
 func OnError(w http.ResponseWriter, r *http.Request, err error) {
 	http.Error(w, err.Error(), http.StatusUnauthorized)
 }
@@ -75,10 +77,6 @@ func New(options ...Options) *JWTMiddleware {
 		opts = options[0]
 	}
 
-	if opts.ErrorHandler == nil {
-		opts.ErrorHandler = OnError
-	}
-
 	if opts.Extractor == nil {
 		opts.Extractor = FromAuthHeader
 	}
@@ -106,8 +104,11 @@ func (m *JWTMiddleware) Handler(h app.Handler) app.Handler {
 
 		// If there was an error, do not continue.
 		if err != nil {
-			m.Options.ErrorHandler(ctx.ResponseWriter, ctx.Request, err)
-			return err
+			if m.Options.ErrorHandler != nil {
+				m.Options.ErrorHandler(ctx.ResponseWriter, ctx.Request, err)
+				return nil
+			}
+			return app.NewHTTPError(http.StatusUnauthorized, "unauthorized", err)
 		}
 
 		return h.Serve(ctx)
@@ -0,0 +1,55 @@
+package jwt_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/jwt"
+	"github.com/syb-devs/goth/log"
+)
+
+// countingWriter counts the calls to WriteHeader
+type countingWriter struct {
+	http.ResponseWriter
+	headers int
+}
+
+func (w *countingWriter) WriteHeader(status int) {
+	w.headers++
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func TestUnauthorized(t *testing.T) {
+	tests := []struct {
+		opts        jwt.Options
+		contentType string
+	}{
+		{jwt.Options{}, app.ProblemContentType},
+		{jwt.Options{ErrorHandler: jwt.OnError}, "text/plain; charset=utf-8"},
+	}
+	for i, test := range tests {
+		a := app.NewApp("test")
+		a.Log = log.NilLogger{}
+		h := jwt.New(test.opts).Handler(app.HandlerFunc(func(ctx *app.Context) error {
+			t.Errorf("test #%d: expecting the handler not to run", i+1)
+			return nil
+		}))
+
+		rec := httptest.NewRecorder()
+		w := &countingWriter{ResponseWriter: rec}
+		req := httptest.NewRequest("GET", "/private", nil)
+		req.Header.Set("Authorization", "Basic abc")
+		ctx := a.NewContextHTTP(w, req)
+		if err := h.Serve(ctx); err != nil {
+			a.HandleError(ctx, err)
+		}
+		if rec.Code != http.StatusUnauthorized || w.headers != 1 {
+			t.Errorf("test #%d: expecting a single 401 response, got %d with %d headers written", i+1, rec.Code, w.headers)
+		}
+		if ctype := rec.Header().Get("Content-Type"); ctype != test.contentType {
+			t.Errorf("test #%d: expecting content type %s, got %s", i+1, test.contentType, ctype)
+		}
+	}
+}
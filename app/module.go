@@ -1,19 +1,128 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 )
 
-var modules = make(map[string]Module)
+var (
+	modules   = make(map[string]Module)
+	modulesMu sync.Mutex
+)
+
+var (
+	// ErrModuleNotFound is returned when a module depends on another one which has not been registered
+	ErrModuleNotFound = errors.New("module not found")
+	// ErrModuleCycle is returned when the module dependencies form a cycle
+	ErrModuleCycle = errors.New("module dependency cycle")
+)
 
 // Module is used to extend the base App with new functionalities
 type Module interface {
 	Name() string
-	Bootstrap(*App, int) error
+	Bootstrap(*App) error
+}
+
+// Dependent is implemented by modules that need other modules to be bootstrapped before them
+type Dependent interface {
+	Dependencies() []string
+}
+
+// Starter is implemented by modules that need to start background tasks (workers, tickers...)
+// once all the modules have been bootstrapped. The context is cancelled when the App shuts down.
+type Starter interface {
+	Start(context.Context, *App) error
+}
+
+// Stopper is implemented by modules that need to stop their background tasks when the App
+// shuts down. Stop should return before the context deadline.
+type Stopper interface {
+	Stop(context.Context, *App) error
+}
+
+// Closer is implemented by modules that need to be notified when the App is closed
+type Closer interface {
+	Close(*App) error
+}
+
+// ModuleError is returned when a module fails at any stage of its lifecycle
+type ModuleError struct {
+	Module string
+	Op     string
+	Err    error
+}
+
+// Error returns the error message, including the module name and the failed operation
+func (e *ModuleError) Error() string {
+	return fmt.Sprintf("module %s: %s: %v", e.Module, e.Op, e.Err)
+}
+
+// Unwrap returns the original error
+func (e *ModuleError) Unwrap() error {
+	return e.Err
+}
+
+// ShutdownError gathers all the errors that happened while shutting down the App
+type ShutdownError struct {
+	Errors []error
+}
+
+// Error returns the messages of all the gathered errors
+func (e *ShutdownError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "shutdown: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the gathered errors matches target, so errors.Is can find them
+func (e *ShutdownError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
 }
 
-// RegisterModule is used by external modulse to register themselves on the App
+// As finds the first gathered error that matches target, so errors.As can find them
+func (e *ShutdownError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends an error, flattening the ones returned by nested shutdown steps
+func (e *ShutdownError) add(err error) {
+	switch err := err.(type) {
+	case nil:
+	case *ShutdownError:
+		e.Errors = append(e.Errors, err.Errors...)
+	default:
+		e.Errors = append(e.Errors, err)
+	}
+}
+
+func (e *ShutdownError) errOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// RegisterModule adds a module to the default set, which is copied to every App created
+// afterwards with NewApp. To register a module only in a given App, use App.Use instead.
 func RegisterModule(module Module) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
 	name := module.Name()
 	if _, exists := modules[name]; exists {
 		panic(fmt.Sprintf("module %s already registered", name))
@@ -21,16 +130,84 @@ func RegisterModule(module Module) {
 	modules[name] = module
 }
 
-// NewBaseModule allocates and returns a BaseModule
-func NewBaseModule(name string) *BaseModule {
+// defaultModules returns a copy of the default module set
+func defaultModules() map[string]Module {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	mods := make(map[string]Module, len(modules))
+	for name, mod := range modules {
+		mods[name] = mod
+	}
+	return mods
+}
+
+// sortModules returns the names of the given modules ordered so that every module
+// comes after its dependencies. Modules without a dependency relation are sorted by name.
+func sortModules(mods map[string]Module) ([]string, error) {
+	names := make([]string, 0, len(mods))
+	for name := range mods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(mods))
+	order := make([]string, 0, len(mods))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s -> %s", ErrModuleCycle, strings.Join(path, " -> "), name)
+		}
+		state[name] = visiting
+		path = append(path, name)
+
+		if dep, ok := mods[name].(Dependent); ok {
+			deps := append([]string(nil), dep.Dependencies()...)
+			sort.Strings(deps)
+			for _, depName := range deps {
+				if _, ok := mods[depName]; !ok {
+					return &ModuleError{Module: name, Op: "dependencies", Err: fmt.Errorf("%w: %s", ErrModuleNotFound, depName)}
+				}
+				if err := visit(depName); err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// NewBaseModule allocates and returns a BaseModule, which depends on the given modules
+func NewBaseModule(name string, deps ...string) *BaseModule {
 	return &BaseModule{
 		name: name,
+		deps: deps,
 	}
 }
 
 // BaseModule is used as a base to implement the module interface with some defaults
 type BaseModule struct {
 	name string
+	deps []string
 }
 
 func (m *BaseModule) mustImplement(method string) {
@@ -40,5 +217,13 @@ func (m *BaseModule) mustImplement(method string) {
 // Name default implementation
 func (m *BaseModule) Name() string { return m.name }
 
+// Dependencies returns the names of the modules this one depends on
+func (m *BaseModule) Dependencies() []string { return m.deps }
+
+// LoadConfig decodes the configuration section named after the module into dest
+func (m *BaseModule) LoadConfig(a *App, dest interface{}) error {
+	return a.Config.Section(m.name, dest)
+}
+
 // Bootstrap default implementation
-func (m *BaseModule) Bootstrap(*App, int) error { m.mustImplement("Bootstrap"); return nil }
+func (m *BaseModule) Bootstrap(*App) error { m.mustImplement("Bootstrap"); return nil }
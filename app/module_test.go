@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/log"
+)
+
+type testModule struct {
+	*BaseModule
+	err error
+}
+
+func (m *testModule) Bootstrap(*App) error { return m.err }
+
+func newTestModule(name string, deps ...string) *testModule {
+	return &testModule{BaseModule: NewBaseModule(name, deps...)}
+}
+
+func TestSortModules(t *testing.T) {
+	mods := map[string]Module{}
+	for _, m := range []*testModule{
+		newTestModule("rest", "db", "auth"),
+		newTestModule("auth", "db"),
+		newTestModule("db"),
+		newTestModule("health"),
+	} {
+		mods[m.Name()] = m
+	}
+
+	order, err := sortModules(mods)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"db", "auth", "health", "rest"}
+	if !reflect.DeepEqual(expected, order) {
+		t.Errorf("expecting order %v, got %v", expected, order)
+	}
+}
+
+func TestSortModulesErrors(t *testing.T) {
+	tests := []struct {
+		mods []*testModule
+		err  error
+	}{
+		{[]*testModule{newTestModule("a", "b"), newTestModule("b", "c"), newTestModule("c", "a")}, ErrModuleCycle},
+		{[]*testModule{newTestModule("a", "a")}, ErrModuleCycle},
+		{[]*testModule{newTestModule("a", "missing")}, ErrModuleNotFound},
+	}
+
+	for i, test := range tests {
+		mods := map[string]Module{}
+		for _, m := range test.mods {
+			mods[m.Name()] = m
+		}
+		_, err := sortModules(mods)
+		if !errors.Is(err, test.err) {
+			t.Errorf("test #%d: expecting error %v, got %v", i+1, test.err, err)
+		}
+	}
+}
+
+func TestBootstrapError(t *testing.T) {
+	cause := errors.New("boom")
+	a := NewApp("test")
+	a.Log = log.NilLogger{}
+	a.Use(
+		newTestModule("db"),
+		&testModule{BaseModule: NewBaseModule("auth", "db"), err: cause},
+	)
+
+	err := a.bootstrap()
+	var merr *ModuleError
+	if !errors.As(err, &merr) || merr.Module != "auth" || merr.Op != "bootstrap" {
+		t.Fatalf("expecting a bootstrap ModuleError for module auth, got %v", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("expecting the original error to be wrapped, got %v", err)
+	}
+	if !reflect.DeepEqual([]string{"db"}, a.order) {
+		t.Errorf("expecting only db to be bootstrapped, got %v", a.order)
+	}
+}
+
+type eventLog struct {
+	sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.Lock()
+	defer l.Unlock()
+	l.events = append(l.events, event)
+}
+
+type lifecycleModule struct {
+	*BaseModule
+	events   *eventLog
+	startErr error
+	stopErr  error
+	block    bool
+}
+
+func (m *lifecycleModule) Bootstrap(*App) error { return nil }
+
+func (m *lifecycleModule) Start(ctx context.Context, a *App) error {
+	m.events.add("start " + m.Name())
+	return m.startErr
+}
+
+func (m *lifecycleModule) Stop(ctx context.Context, a *App) error {
+	m.events.add("stop " + m.Name())
+	if m.block {
+		<-make(chan struct{})
+	}
+	return m.stopErr
+}
+
+func TestStartStop(t *testing.T) {
+	events := &eventLog{}
+	stopErr := errors.New("stop failed")
+	a := NewApp("test")
+	a.Log = log.NilLogger{}
+	a.Use(
+		&lifecycleModule{BaseModule: NewBaseModule("db"), events: events},
+		&lifecycleModule{BaseModule: NewBaseModule("worker", "db"), events: events, stopErr: stopErr},
+		&lifecycleModule{BaseModule: NewBaseModule("scraper", "worker"), events: events, block: true},
+	)
+	if err := a.bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	err := a.stop(50 * time.Millisecond)
+	expected := []string{"start db", "start worker", "start scraper", "stop scraper", "stop worker", "stop db"}
+	if !reflect.DeepEqual(expected, events.events) {
+		t.Errorf("expecting events %v, got %v", expected, events.events)
+	}
+	if !errors.Is(err, stopErr) {
+		t.Errorf("expecting the stop error to be surfaced, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting the deadline error to be surfaced, got %v", err)
+	}
+	var merr *ModuleError
+	if !errors.As(err, &merr) || merr.Op != "stop" {
+		t.Errorf("expecting a stop *ModuleError to be surfaced, got %v", err)
+	}
+}
+
+func TestStartError(t *testing.T) {
+	events := &eventLog{}
+	startErr := errors.New("start failed")
+	a := NewApp("test")
+	a.Log = log.NilLogger{}
+	a.Use(
+		&lifecycleModule{BaseModule: NewBaseModule("db"), events: events},
+		&lifecycleModule{BaseModule: NewBaseModule("worker", "db"), events: events, startErr: startErr},
+	)
+	if err := a.bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	err := a.start(context.Background())
+	var merr *ModuleError
+	if !errors.As(err, &merr) || merr.Module != "worker" || merr.Op != "start" {
+		t.Fatalf("expecting a start ModuleError for module worker, got %v", err)
+	}
+	a.stop(time.Second)
+	expected := []string{"start db", "start worker", "stop db"}
+	if !reflect.DeepEqual(expected, events.events) {
+		t.Errorf("expecting events %v, got %v", expected, events.events)
+	}
+}
+
+func TestAppModuleRegistry(t *testing.T) {
+	RegisterModule(newTestModule("default"))
+	defer delete(modules, "default")
+
+	a := NewApp("public")
+	b := NewApp("admin")
+	a.Use(newTestModule("public-only"))
+	b.RemoveModule("default")
+
+	if _, ok := a.Module("default"); !ok {
+		t.Error("expecting the default module to be registered in the app")
+	}
+	if _, ok := b.Module("default"); ok {
+		t.Error("expecting the default module to be removed from the second app")
+	}
+	if _, ok := b.Module("public-only"); ok {
+		t.Error("expecting modules used in one app not to be shared with other apps")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expecting a panic when using a module twice")
+		}
+	}()
+	a.Use(newTestModule("public-only"))
+}
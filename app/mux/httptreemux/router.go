@@ -1,36 +1,124 @@
 package httptreemux
 
 import (
+	"context"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/syb-devs/goth/app"
 
 	"github.com/dimfeld/httptreemux"
 )
 
+// fallbackKey is the request context key used to ask a route handler to serve its path fallback
+type fallbackKey struct{}
+
 // Muxer resolves requests to the corresponding HTTP handler
 type Muxer struct {
 	*httptreemux.TreeMux
 	ctxGen app.CtxGenHTTP
+
+	mu        sync.RWMutex
+	fallbacks map[string]app.Handler
+	// routes holds the registered handlers by method and path, and implicit the routes serving
+	// the empty value of the catch-alls, which the TreeMux does not match (i.e. /static/ for
+	// /static/*path)
+	routes   map[string]httptreemux.HandlerFunc
+	implicit map[string]bool
 }
 
 // New returns a Muxer object
 func New(ctxGen app.CtxGenHTTP) *Muxer {
-	return &Muxer{
-		ctxGen:  ctxGen,
-		TreeMux: httptreemux.New(),
+	rt := &Muxer{
+		ctxGen:    ctxGen,
+		TreeMux:   httptreemux.New(),
+		fallbacks: make(map[string]app.Handler),
+		routes:    make(map[string]httptreemux.HandlerFunc),
+		implicit:  make(map[string]bool),
 	}
+	rt.TreeMux.MethodNotAllowedHandler = rt.methodNotAllowed
+	return rt
 }
 
-// Handle registers an HTTP handler to a given verb / path combination
+// Handle registers an HTTP handler to a given verb / path combination.
+// The catch-alls also match the empty rest of the path, after the trailing slash.
 func (rt *Muxer) Handle(verb, path string, h app.Handler) {
-	rt.TreeMux.Handle(verb, path, rt.wrapHandler(h))
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	handler := rt.wrapHandler(path, h)
+	key := verb + " " + path
+	rt.routes[key] = handler
+	// An implicit route already serves the path, looking up the registered handler
+	if !rt.implicit[key] {
+		rt.TreeMux.Handle(verb, path, handler)
+	}
+
+	prefix, name, ok := catchAll(path)
+	if !ok {
+		return
+	}
+	key = verb + " " + prefix
+	if _, exists := rt.routes[key]; exists || rt.implicit[key] {
+		return
+	}
+	rt.implicit[key] = true
+	rt.TreeMux.Handle(verb, prefix, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		rt.mu.RLock()
+		explicit, ok := rt.routes[key]
+		rt.mu.RUnlock()
+		if ok {
+			explicit(w, r, nil)
+			return
+		}
+		handler(w, r, map[string]string{name: ""})
+	})
+}
+
+// catchAll splits a path ending with a catch-all in the prefix, including the trailing slash,
+// and the catch-all name
+func catchAll(path string) (prefix, name string, ok bool) {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 || !strings.HasPrefix(path[i+1:], "*") {
+		return "", "", false
+	}
+	return path[:i+1], path[i+2:], true
+}
+
+// HandleFallback registers the handler for the requests matching the given path,
+// but none of its methods, including OPTIONS requests
+func (rt *Muxer) HandleFallback(path string, h app.Handler) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.fallbacks[path] = h
 }
 
-func (rt *Muxer) wrapHandler(h app.Handler) httptreemux.HandlerFunc {
+func (rt *Muxer) wrapHandler(path string, h app.Handler) httptreemux.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request, urlParams map[string]string) {
+		handler := h
+		if methods, ok := r.Context().Value(fallbackKey{}).(map[string]httptreemux.HandlerFunc); ok {
+			rt.mu.RLock()
+			fallback, found := rt.fallbacks[path]
+			rt.mu.RUnlock()
+			if !found {
+				httptreemux.MethodNotAllowedHandler(w, r, methods)
+				return
+			}
+			handler = fallback
+		}
 		ctx := rt.ctxGen(w, r)
 		ctx.URLParams = app.URLParams(urlParams)
-		h.Serve(ctx)
+		app.Dispatch(ctx, handler)
+	}
+}
+
+// methodNotAllowed is called by the TreeMux when the path matches, but the method does not.
+// As the TreeMux does not tell the matched path, any of the path handlers is asked to serve
+// the path fallback instead.
+func (rt *Muxer) methodNotAllowed(w http.ResponseWriter, r *http.Request, methods map[string]httptreemux.HandlerFunc) {
+	for _, h := range methods {
+		h(w, r.WithContext(context.WithValue(r.Context(), fallbackKey{}, methods)), nil)
+		return
 	}
+	httptreemux.MethodNotAllowedHandler(w, r, methods)
 }
@@ -0,0 +1,15 @@
+package httptreemux_test
+
+import (
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/mux/httptreemux"
+	"github.com/syb-devs/goth/app/mux/muxtest"
+)
+
+func TestConformance(t *testing.T) {
+	muxtest.Run(t, func(ctxGen app.CtxGenHTTP) muxtest.Muxer {
+		return httptreemux.New(ctxGen)
+	})
+}
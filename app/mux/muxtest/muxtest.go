@@ -0,0 +1,251 @@
+// Package muxtest implements a conformance suite for app.Muxer implementations.
+//
+// Every Muxer should pass it to guarantee that handlers behave the same way,
+// regardless of the Muxer the App is using:
+//
+//	func TestConformance(t *testing.T) {
+//		muxtest.Run(t, func(ctxGen app.CtxGenHTTP) muxtest.Muxer {
+//			return mymux.New(ctxGen)
+//		})
+//	}
+package muxtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/log"
+)
+
+const (
+	// RouteHeader is the response header used by the suite handlers to identify the matched route
+	RouteHeader = "X-Muxtest-Route"
+	// ChainHeader is the response header set by the middleware chain the suite routes are wrapped with
+	ChainHeader = "X-Muxtest-Chain"
+	// MountRoute is the route identifier of the net/http handler mounted at /std
+	MountRoute = "MOUNT /std"
+)
+
+// Muxer is an app.Muxer which can serve HTTP requests
+type Muxer interface {
+	app.Muxer
+	http.Handler
+}
+
+// Factory is a function that returns a new Muxer using the given context generator
+type Factory func(ctxGen app.CtxGenHTTP) Muxer
+
+// Route is a route registered in the Muxer under test
+type Route struct {
+	Method  string
+	Pattern string
+}
+
+// ID returns the identifier of the route
+func (r Route) ID() string {
+	return r.Method + " " + r.Pattern
+}
+
+// Request is a request made against the Muxer under test, with the expected outcome
+type Request struct {
+	Method string
+	Path   string
+	// Status is the expected response status code
+	Status int
+	// Route is the expected matched route, only checked for 200 responses
+	Route string
+	// Params are the expected URL params, only checked for 200 responses
+	Params app.URLParams
+	// Location is the expected Location header, only checked if not empty
+	Location string
+	// Allow is the expected Allow header, only checked if not empty
+	Allow string
+}
+
+// Routes is the set of routes registered by the suite
+var Routes = []Route{
+	{"GET", "/"},
+	{"GET", "/users"},
+	{"POST", "/users"},
+	{"GET", "/users/new"},
+	{"GET", "/users/:id"},
+	{"PUT", "/users/:id"},
+	{"GET", "/users/:id/edit"},
+	{"GET", "/users/:id/posts/:postID"},
+	{"GET", "/static/*filepath"},
+	{"GET", "/static/special/file"},
+	{"GET", "/docs/"},
+	{"GET", "/files/*path"},
+	{"GET", "/orders/:n<int>"},
+	{"GET", "/tags/:slug<[a-z0-9-]+>"},
+}
+
+// Requests is the set of requests checked by the suite
+var Requests = []Request{
+	// Static routes
+	{Method: "GET", Path: "/", Status: 200, Route: "GET /"},
+	{Method: "GET", Path: "/users", Status: 200, Route: "GET /users"},
+	{Method: "GET", Path: "/docs/", Status: 200, Route: "GET /docs/"},
+
+	// Method routing
+	{Method: "POST", Path: "/users", Status: 200, Route: "POST /users"},
+	{Method: "PUT", Path: "/users/42", Status: 200, Route: "PUT /users/:id", Params: app.URLParams{"id": "42"}},
+
+	// Params
+	{Method: "GET", Path: "/users/42", Status: 200, Route: "GET /users/:id", Params: app.URLParams{"id": "42"}},
+	{Method: "GET", Path: "/users/42/edit", Status: 200, Route: "GET /users/:id/edit", Params: app.URLParams{"id": "42"}},
+	{Method: "GET", Path: "/users/42/posts/7", Status: 200, Route: "GET /users/:id/posts/:postID", Params: app.URLParams{"id": "42", "postID": "7"}},
+	{Method: "GET", Path: "/users/john%20doe", Status: 200, Route: "GET /users/:id", Params: app.URLParams{"id": "john doe"}},
+	{Method: "GET", Path: "/users/a%2Fb", Status: 200, Route: "GET /users/:id", Params: app.URLParams{"id": "a/b"}},
+
+	// Wildcards
+	{Method: "GET", Path: "/static/css/app.css", Status: 200, Route: "GET /static/*filepath", Params: app.URLParams{"filepath": "css/app.css"}},
+	{Method: "GET", Path: "/static/special/other", Status: 200, Route: "GET /static/*filepath", Params: app.URLParams{"filepath": "special/other"}},
+	{Method: "GET", Path: "/files/", Status: 200, Route: "GET /files/*path", Params: app.URLParams{"path": ""}},
+	{Method: "GET", Path: "/files", Status: 301, Location: "/files/"},
+	{Method: "HEAD", Path: "/files/", Status: 200, Route: "GET /files/*path"},
+	{Method: "POST", Path: "/files/", Status: 405, Allow: "GET, HEAD, OPTIONS"},
+
+	// Precedence: static > param > wildcard
+	{Method: "GET", Path: "/users/new", Status: 200, Route: "GET /users/new"},
+	{Method: "GET", Path: "/users/new/edit", Status: 200, Route: "GET /users/:id/edit", Params: app.URLParams{"id": "new"}},
+	{Method: "GET", Path: "/static/special/file", Status: 200, Route: "GET /static/special/file"},
+
+	// Constrained params
+	{Method: "GET", Path: "/orders/12", Status: 200, Route: "GET /orders/:n<int>", Params: app.URLParams{"n": "12"}},
+	{Method: "GET", Path: "/orders/twelve", Status: 400},
+	{Method: "GET", Path: "/tags/go-1", Status: 200, Route: "GET /tags/:slug<[a-z0-9-]+>", Params: app.URLParams{"slug": "go-1"}},
+	{Method: "GET", Path: "/tags/Go_1", Status: 404},
+
+	// Mounted net/http handler
+	{Method: "GET", Path: "/std/", Status: 200, Route: MountRoute, Params: app.URLParams{"path": ""}},
+	{Method: "GET", Path: "/std/a/b", Status: 200, Route: MountRoute, Params: app.URLParams{"path": "a/b"}},
+	{Method: "DELETE", Path: "/std/a", Status: 200, Route: MountRoute, Params: app.URLParams{"path": "a"}},
+	{Method: "GET", Path: "/std", Status: 301, Location: "/std/"},
+
+	// Not found
+	{Method: "GET", Path: "/missing", Status: 404},
+	{Method: "GET", Path: "/users/42/unknown", Status: 404},
+	{Method: "GET", Path: "/users/42/posts", Status: 404},
+
+	// Method not allowed
+	{Method: "DELETE", Path: "/users", Status: 405, Allow: "GET, HEAD, OPTIONS, POST"},
+	{Method: "POST", Path: "/users/42", Status: 405, Allow: "GET, HEAD, OPTIONS, PUT"},
+	{Method: "POST", Path: "/static/css/app.css", Status: 405, Allow: "GET, HEAD, OPTIONS"},
+
+	// HEAD requests served by GET handlers
+	{Method: "HEAD", Path: "/users", Status: 200, Route: "GET /users"},
+	{Method: "HEAD", Path: "/users/42", Status: 200, Route: "GET /users/:id"},
+
+	// OPTIONS
+	{Method: "OPTIONS", Path: "/users", Status: 204, Allow: "GET, HEAD, OPTIONS, POST"},
+	{Method: "OPTIONS", Path: "/users/new", Status: 204, Allow: "GET, HEAD, OPTIONS"},
+	{Method: "OPTIONS", Path: "/missing", Status: 404},
+
+	// Trailing slash redirects
+	{Method: "GET", Path: "/users/", Status: 301, Location: "/users"},
+	{Method: "GET", Path: "/users/42/", Status: 301, Location: "/users/42"},
+	{Method: "GET", Path: "/docs", Status: 301, Location: "/docs/"},
+}
+
+// Run runs the conformance suite against the Muxer returned by the factory.
+// The routes are registered through an App, wrapped with a middleware chain.
+func Run(t *testing.T, factory Factory) {
+	a := app.NewApp("muxtest")
+	a.Log = log.NilLogger{}
+	mux := factory(a.NewContextHTTP)
+	a.SetMuxer(mux)
+	a.AddChain(chain.New(chainMiddleware), "muxtest")
+	g := a.Group("", "muxtest")
+	for _, route := range Routes {
+		g.Handle(route.Method, route.Pattern, routeHandler(route))
+	}
+	g.Mount("/std", mountHandler())
+
+	for _, req := range Requests {
+		t.Run(req.Method+" "+req.Path, func(t *testing.T) {
+			Check(t, mux, req)
+		})
+	}
+}
+
+// Check makes a request against the Muxer and checks the response
+func Check(t *testing.T, mux http.Handler, req Request) {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(req.Method, req.Path, nil))
+
+	if rec.Code != req.Status {
+		t.Fatalf("expecting status %d, got %d", req.Status, rec.Code)
+	}
+	if req.Location != "" {
+		if loc := rec.Header().Get("Location"); loc != req.Location {
+			t.Errorf("expecting Location %s, got %s", req.Location, loc)
+		}
+	}
+	if req.Allow != "" {
+		if allow := rec.Header().Get("Allow"); allow != req.Allow {
+			t.Errorf("expecting Allow %s, got %s", req.Allow, allow)
+		}
+	}
+	if req.Status != http.StatusNotFound && req.Status != http.StatusMovedPermanently {
+		if rec.Header().Get(ChainHeader) == "" {
+			t.Errorf("expecting the response to go through the middleware chain")
+		}
+	}
+	if req.Method == http.MethodHead && rec.Body.Len() != 0 {
+		t.Errorf("expecting empty body for HEAD request, got %q", rec.Body.String())
+	}
+	if req.Status != http.StatusOK || req.Route == "" {
+		return
+	}
+	if route := rec.Header().Get(RouteHeader); route != req.Route {
+		t.Errorf("expecting route %s, got %s", req.Route, route)
+	}
+	if req.Method == http.MethodHead {
+		return
+	}
+	params := app.URLParams{}
+	if err := json.NewDecoder(rec.Body).Decode(&params); err != nil {
+		t.Fatalf("error decoding params: %v", err)
+	}
+	if len(params) == 0 && len(req.Params) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(req.Params, params) {
+		t.Errorf("expecting params %v, got %v", req.Params, params)
+	}
+}
+
+// routeHandler returns a handler that identifies the route and encodes the URL params
+func routeHandler(route Route) app.Handler {
+	return app.HandlerFunc(func(ctx *app.Context) error {
+		ctx.Header().Set(RouteHeader, route.ID())
+		return ctx.Encode(ctx.URLParams)
+	})
+}
+
+// mountHandler returns a net/http handler that identifies the mount route and encodes the URL params
+func mountHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := app.ContextFromRequest(r)
+		if !ok {
+			http.Error(w, "missing app.Context", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(RouteHeader, MountRoute)
+		json.NewEncoder(w).Encode(ctx.URLParams)
+	})
+}
+
+// chainMiddleware flags the responses served through the suite middleware chain
+func chainMiddleware(h app.Handler) app.Handler {
+	return app.HandlerFunc(func(ctx *app.Context) error {
+		ctx.Header().Set(ChainHeader, "true")
+		return h.Serve(ctx)
+	})
+}
@@ -0,0 +1,138 @@
+// Package stdmux implements an app.Muxer on top of net/http, using its own routing tree.
+//
+// Patterns are made of slash separated segments, which can be static (/users),
+// params matching a whole segment (/users/:id) or catch-alls matching the rest
+// of the path (/static/*filepath). Static segments take precedence over params,
+// and params over catch-alls, backtracking when a branch does not lead to a route.
+package stdmux
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/syb-devs/goth/app"
+)
+
+// Muxer resolves requests to the corresponding HTTP handler
+type Muxer struct {
+	mu     sync.RWMutex
+	root   *node
+	ctxGen app.CtxGenHTTP
+}
+
+// New returns a Muxer object
+func New(ctxGen app.CtxGenHTTP) *Muxer {
+	return &Muxer{
+		root:   newNode(),
+		ctxGen: ctxGen,
+	}
+}
+
+// Handle registers an HTTP handler to a given verb / path combination.
+// It panics if the pattern is malformed or conflicts with an existing route.
+func (mux *Muxer) Handle(verb, path string, h app.Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.root.add(verb, path, h)
+}
+
+// HandleFallback registers the handler for the requests matching the given path pattern,
+// but none of its methods. Without a fallback, those requests get a 405 Method Not Allowed response.
+func (mux *Muxer) HandleFallback(path string, h app.Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.root.insert(path).fallback = h
+}
+
+// ServeHTTP dispatches the request to the handler whose pattern matches the request path and method.
+// Paths differing from the route pattern only in the trailing slash are redirected.
+func (mux *Muxer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, hasSlash := splitSlash(r.URL.EscapedPath())
+
+	mux.mu.RLock()
+	m := mux.lookup(r.Method, splitPath(path))
+	var (
+		h       app.Handler
+		ok      bool
+		allowed []string
+	)
+	if m != nil {
+		if h, ok = m.node.handler(r.Method); !ok {
+			allowed = m.node.methods()
+		}
+	}
+	mux.mu.RUnlock()
+
+	if m == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	n := m.node
+	if n.wildcard {
+		switch {
+		case m.empty && !hasSlash && path != "/":
+			// The empty catch-all value is matched after the slash, i.e. /static/ for /static/*path
+			redirect(w, r, path, true)
+			return
+		case hasSlash && !m.empty:
+			// The trailing slash belongs to the catch-all value
+			m.params[len(m.params)-1] += "/"
+		}
+	} else if hasSlash != n.addSlash {
+		redirect(w, r, path, n.addSlash)
+		return
+	}
+
+	if !ok {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := mux.ctxGen(w, r)
+	ctx.URLParams = params(n, m.params)
+	app.Dispatch(ctx, h)
+}
+
+// lookup finds the node for the given path segments, preferring the ones having a handler for the method.
+// HEAD requests fall back to GET handlers, and then any node matching the path is returned.
+func (mux *Muxer) lookup(method string, segments []string) *match {
+	if m := mux.root.search(method, segments); m != nil {
+		return m
+	}
+	if method == http.MethodHead {
+		if m := mux.root.search(http.MethodGet, segments); m != nil {
+			return m
+		}
+	}
+	return mux.root.search("", segments)
+}
+
+// params builds the URLParams for the matched node from the captured values
+func params(n *node, values []string) app.URLParams {
+	names := n.paramNames()
+	if len(names) == 0 {
+		return nil
+	}
+	p := make(app.URLParams, len(names))
+	for i, name := range names {
+		if i < len(values) {
+			p[name] = values[i]
+		}
+	}
+	return p
+}
+
+// redirect sends a permanent redirect to the given path, with or without trailing slash,
+// keeping the query string
+func redirect(w http.ResponseWriter, r *http.Request, path string, addSlash bool) {
+	if addSlash {
+		path += "/"
+	}
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, path, http.StatusMovedPermanently)
+}
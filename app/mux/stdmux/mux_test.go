@@ -0,0 +1,45 @@
+package stdmux_test
+
+import (
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/mux/muxtest"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+)
+
+func TestConformance(t *testing.T) {
+	muxtest.Run(t, func(ctxGen app.CtxGenHTTP) muxtest.Muxer {
+		return stdmux.New(ctxGen)
+	})
+}
+
+func TestHandlePanics(t *testing.T) {
+	tests := []struct {
+		existing string
+		pattern  string
+	}{
+		{"", "users"},
+		{"", "/users//posts"},
+		{"", "/static/*filepath/more"},
+		{"/users/:id", "/users/:name"},
+		{"/static/*filepath", "/static/*path"},
+		{"/users", "/users"},
+	}
+
+	h := app.HandlerFunc(func(ctx *app.Context) error { return nil })
+	for i, test := range tests {
+		mux := stdmux.New(nil)
+		if test.existing != "" {
+			mux.Handle("GET", test.existing, h)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("test #%d: expecting panic registering %s", i+1, test.pattern)
+				}
+			}()
+			mux.Handle("GET", test.pattern, h)
+		}()
+	}
+}
@@ -0,0 +1,210 @@
+package stdmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/syb-devs/goth/app"
+)
+
+// node is a path segment in the routing tree
+type node struct {
+	// static children, by segment
+	static map[string]*node
+	// param child (:name), matching any non-empty segment
+	param *node
+	// catchAll child (*name), matching the rest of the path
+	catchAll *node
+	// name of the param or catch-all represented by this node
+	name string
+	// wildcard is true for catch-all nodes
+	wildcard bool
+	// handlers by HTTP method
+	handlers map[string]app.Handler
+	// fallback serves the requests whose method has no handler
+	fallback app.Handler
+	// pattern registered for this node
+	pattern string
+	// addSlash is true when the pattern was registered with a trailing slash
+	addSlash bool
+}
+
+func newNode() *node {
+	return &node{static: make(map[string]*node)}
+}
+
+// add registers a handler for the given method and path pattern
+func (n *node) add(method, pattern string, h app.Handler) {
+	n = n.insert(pattern)
+	if n.handlers == nil {
+		n.handlers = make(map[string]app.Handler)
+	}
+	if _, exists := n.handlers[method]; exists {
+		panic(fmt.Sprintf("a handler is already registered for %s %s", method, pattern))
+	}
+	n.handlers[method] = h
+}
+
+// insert returns the node for the given path pattern, creating the missing ones
+func (n *node) insert(pattern string) *node {
+	if pattern == "" || pattern[0] != '/' {
+		panic(fmt.Sprintf("path %q must begin with /", pattern))
+	}
+	path, addSlash := splitSlash(pattern)
+	segments := splitPath(path)
+
+	for i, seg := range segments {
+		switch {
+		case seg == "":
+			panic(fmt.Sprintf("empty segment in path %q", pattern))
+		case seg[0] == ':':
+			if n.param == nil {
+				n.param = newNode()
+				n.param.name = seg[1:]
+			} else if n.param.name != seg[1:] {
+				panic(fmt.Sprintf("param :%s in path %q conflicts with existing param :%s", seg[1:], pattern, n.param.name))
+			}
+			n = n.param
+		case seg[0] == '*':
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("catch-all %s must be the last segment of path %q", seg, pattern))
+			}
+			if n.catchAll == nil {
+				n.catchAll = newNode()
+				n.catchAll.name = seg[1:]
+				n.catchAll.wildcard = true
+			} else if n.catchAll.name != seg[1:] {
+				panic(fmt.Sprintf("catch-all %s in path %q conflicts with existing catch-all *%s", seg, pattern, n.catchAll.name))
+			}
+			n = n.catchAll
+		default:
+			child, ok := n.static[seg]
+			if !ok {
+				child = newNode()
+				n.static[seg] = child
+			}
+			n = child
+		}
+	}
+
+	n.pattern = pattern
+	n.addSlash = addSlash
+	return n
+}
+
+// match is the result of a tree lookup
+type match struct {
+	node   *node
+	params []string
+	// empty is true when a catch-all matched the empty remainder of the path
+	empty bool
+}
+
+// search looks for the node matching the given raw path segments. Static segments take
+// precedence over params, and params over catch-alls. If method is not empty, only the nodes
+// having a handler for it are considered a match, falling back to other branches otherwise.
+func (n *node) search(method string, segments []string) *match {
+	if len(segments) == 0 {
+		if n.matches(method) {
+			return &match{node: n}
+		}
+		if n.catchAll != nil && n.catchAll.matches(method) {
+			return &match{node: n.catchAll, params: []string{""}, empty: true}
+		}
+		return nil
+	}
+
+	seg := unescape(segments[0])
+	if child, ok := n.static[seg]; ok {
+		if m := child.search(method, segments[1:]); m != nil {
+			return m
+		}
+	}
+	if n.param != nil && segments[0] != "" {
+		if m := n.param.search(method, segments[1:]); m != nil {
+			m.params = append([]string{seg}, m.params...)
+			return m
+		}
+	}
+	if n.catchAll != nil && n.catchAll.matches(method) {
+		return &match{
+			node:   n.catchAll,
+			params: []string{unescape(strings.Join(segments, "/"))},
+		}
+	}
+	return nil
+}
+
+func (n *node) matches(method string) bool {
+	if method == "" {
+		return len(n.handlers) > 0
+	}
+	_, ok := n.handlers[method]
+	return ok
+}
+
+// paramNames returns the names of the params and catch-alls of the node pattern, in order
+func (n *node) paramNames() []string {
+	var names []string
+	for _, seg := range splitPath(n.pattern) {
+		if seg != "" && (seg[0] == ':' || seg[0] == '*') {
+			names = append(names, seg[1:])
+		}
+	}
+	return names
+}
+
+// handler returns the handler for the given method. HEAD requests are served by the GET handler
+// if there is no HEAD one, and the fallback handler is returned for any other method, if set.
+func (n *node) handler(method string) (app.Handler, bool) {
+	if h, ok := n.handlers[method]; ok {
+		return h, true
+	}
+	if h, ok := n.handlers[http.MethodGet]; ok && method == http.MethodHead {
+		return h, true
+	}
+	return n.fallback, n.fallback != nil
+}
+
+// methods returns the sorted list of methods with a registered handler
+func (n *node) methods() []string {
+	methods := make([]string, 0, len(n.handlers)+1)
+	for m := range n.handlers {
+		methods = append(methods, m)
+	}
+	if _, ok := n.handlers[http.MethodGet]; ok {
+		if _, ok := n.handlers[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// splitSlash removes the trailing slash of a path, reporting if it had one
+func splitSlash(path string) (string, bool) {
+	if len(path) > 1 && path[len(path)-1] == '/' {
+		return path[:len(path)-1], true
+	}
+	return path, false
+}
+
+// splitPath splits a path in segments, without the leading slash
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func unescape(s string) string {
+	u, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	return u
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ParamType checks if a value is valid for the URL parameters constrained to the type in
+// the route patterns, i.e. /todos/:id<objectid>
+type ParamType func(value string) bool
+
+var (
+	paramTypes = map[string]ParamType{
+		"int":      isInt,
+		"objectid": bson.IsObjectIdHex,
+	}
+	paramTypesMu sync.RWMutex
+)
+
+// RegisterParamType registers a ParamType with the given name, so it can be used as a
+// URL parameter constraint in the route patterns. It panics if the name is already registered.
+func RegisterParamType(name string, t ParamType) {
+	paramTypesMu.Lock()
+	defer paramTypesMu.Unlock()
+	if _, exists := paramTypes[name]; exists {
+		panic(fmt.Sprintf("URL parameter type %s already registered", name))
+	}
+	paramTypes[name] = t
+}
+
+func lookupParamType(name string) (ParamType, bool) {
+	paramTypesMu.RLock()
+	defer paramTypesMu.RUnlock()
+	t, ok := paramTypes[name]
+	return t, ok
+}
+
+func isInt(value string) bool {
+	_, err := strconv.Atoi(value)
+	return err == nil
+}
+
+// Int returns the value of the URL parameter with the given name as an int.
+// The returned error is a 400 Bad Request HTTPError if the value is not an integer.
+func (p URLParams) Int(name string) (int, error) {
+	n, err := strconv.Atoi(p[name])
+	if err != nil {
+		return 0, invalidParamError(name, "int")
+	}
+	return n, nil
+}
+
+// ObjectID returns the value of the URL parameter with the given name as a bson.ObjectId.
+// The returned error is a 400 Bad Request HTTPError if the value is not a valid hex ObjectId.
+func (p URLParams) ObjectID(name string) (bson.ObjectId, error) {
+	value := p[name]
+	if !bson.IsObjectIdHex(value) {
+		return "", invalidParamError(name, "objectid")
+	}
+	return bson.ObjectIdHex(value), nil
+}
+
+// invalidParamError returns the error for a URL parameter whose value is not of the expected type
+func invalidParamError(name, typ string) *HTTPError {
+	return &HTTPError{
+		Status:  http.StatusBadRequest,
+		Code:    "invalid_url_param",
+		Message: fmt.Sprintf("invalid URL parameter %s", name),
+		Details: map[string]string{name: fmt.Sprintf("must be of type %s", typ)},
+	}
+}
+
+// paramConstraint is the constraint declared for a URL parameter in a route pattern.
+// It is either a registered ParamType or a regular expression the whole value must match.
+type paramConstraint struct {
+	name string
+	expr string
+	typ  ParamType
+	re   *regexp.Regexp
+}
+
+// check returns a 400 Bad Request HTTPError if the value is not valid for the parameter type,
+// or ErrNotFound if it does not match the parameter regular expression, as the route does not
+// apply to the request path.
+func (c paramConstraint) check(value string) error {
+	if c.typ != nil {
+		if !c.typ(value) {
+			return invalidParamError(c.name, c.expr)
+		}
+		return nil
+	}
+	if !c.re.MatchString(value) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// parsePattern removes the constraints of the parameters of a route pattern (i.e. :id<int>),
+// returning the plain pattern for the Muxer, and the constraints apart. As parameters match
+// a single path segment, regular expressions can not contain slashes.
+// It panics if a constraint is not a registered ParamType nor a valid regular expression.
+func parsePattern(pattern string) (string, []paramConstraint) {
+	segments := strings.Split(pattern, "/")
+	var constraints []paramConstraint
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, ":") || !strings.HasSuffix(seg, ">") {
+			continue
+		}
+		open := strings.IndexByte(seg, '<')
+		if open == -1 {
+			continue
+		}
+		c := paramConstraint{name: seg[1:open], expr: seg[open+1 : len(seg)-1]}
+		if t, ok := lookupParamType(c.expr); ok {
+			c.typ = t
+		} else {
+			re, err := regexp.Compile("^(?:" + c.expr + ")$")
+			if err != nil {
+				panic(fmt.Sprintf("invalid constraint for URL parameter %s in path %s: %v", c.name, pattern, err))
+			}
+			c.re = re
+		}
+		constraints = append(constraints, c)
+		segments[i] = ":" + c.name
+	}
+	return strings.Join(segments, "/"), constraints
+}
+
+// constrain wraps the handler, so it is only run if the URL parameters satisfy the given constraints
+func constrain(h Handler, constraints []paramConstraint) Handler {
+	if len(constraints) == 0 {
+		return h
+	}
+	return HandlerFunc(func(ctx *Context) error {
+		for _, c := range constraints {
+			if err := c.check(ctx.URLParams.ByName(c.name)); err != nil {
+				return err
+			}
+		}
+		return h.Serve(ctx)
+	})
+}
@@ -0,0 +1,122 @@
+package app_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/log"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestURLParamsTyped(t *testing.T) {
+	id := bson.NewObjectId()
+	params := app.URLParams{"n": "42", "id": id.Hex(), "bad": "x1"}
+
+	if n, err := params.Int("n"); n != 42 || err != nil {
+		t.Errorf("expecting 42, got %d, %v", n, err)
+	}
+	if oid, err := params.ObjectID("id"); oid != id || err != nil {
+		t.Errorf("expecting %s, got %s, %v", id, oid, err)
+	}
+
+	var herr *app.HTTPError
+	if _, err := params.Int("bad"); !errors.As(err, &herr) || herr.Status != http.StatusBadRequest {
+		t.Errorf("expecting a 400 HTTPError, got %v", err)
+	}
+	if _, err := params.ObjectID("bad"); !errors.As(err, &herr) || herr.Status != http.StatusBadRequest {
+		t.Errorf("expecting a 400 HTTPError, got %v", err)
+	}
+}
+
+func TestParamConstraints(t *testing.T) {
+	app.RegisterParamType("even", func(v string) bool { return len(v) > 0 && (v[len(v)-1]-'0')%2 == 0 })
+
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	mux := &recordingMuxer{}
+	a.SetMuxer(mux)
+	a.AddChain(chain.New(), "main")
+	var served bool
+	r := a.Group("/api", "main").HandleFunc("GET", "/:a<int>/:b<even>/:c<[a-z]+>", func(*app.Context) error {
+		served = true
+		return nil
+	})
+
+	if r.Pattern != "/api/:a/:b/:c" {
+		t.Errorf("expecting the pattern without constraints, got %s", r.Pattern)
+	}
+	if expected := map[string]string{"a": "int", "b": "even", "c": "[a-z]+"}; !reflect.DeepEqual(expected, r.Params) {
+		t.Errorf("expecting params %v, got %v", expected, r.Params)
+	}
+	if mux.routes[0].path != r.Pattern {
+		t.Errorf("expecting the muxer route %s, got %s", r.Pattern, mux.routes[0].path)
+	}
+
+	tests := []struct {
+		params app.URLParams
+		status int
+	}{
+		{app.URLParams{"a": "1", "b": "2", "c": "x"}, http.StatusOK},
+		{app.URLParams{"a": "one", "b": "2", "c": "x"}, http.StatusBadRequest},
+		{app.URLParams{"a": "1", "b": "3", "c": "x"}, http.StatusBadRequest},
+		{app.URLParams{"a": "1", "b": "2", "c": "X"}, http.StatusNotFound},
+	}
+	for i, test := range tests {
+		served = false
+		rec := httptest.NewRecorder()
+		ctx := a.NewContextHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		ctx.URLParams = test.params
+		app.Dispatch(ctx, mux.routes[0].h)
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, rec.Code)
+		}
+		if served != (test.status == http.StatusOK) {
+			t.Errorf("test #%d: expecting served %v, got %v", i+1, test.status == http.StatusOK, served)
+		}
+	}
+}
+
+func TestInvalidParamConstraint(t *testing.T) {
+	a := app.NewApp("test")
+	a.SetMuxer(&recordingMuxer{})
+	defer func() {
+		if recover() == nil {
+			t.Error("expecting a panic for an invalid constraint")
+		}
+	}()
+	a.Handle("GET", "/:a<[a-z>", app.HandlerFunc(func(*app.Context) error { return nil }))
+}
+
+func TestConflictingParamConstraints(t *testing.T) {
+	noop := app.HandlerFunc(func(*app.Context) error { return nil })
+	tests := []struct {
+		first, second string
+		conflict      bool
+	}{
+		{"/users/:id<int>", "/users/:name", true},
+		{"/users/:id", "/users/:id<[a-z]+>", true},
+		{"/users/:id<int>", "/users/:id<int>/posts", false},
+		{"/users/:id<int>", "/users/me", false},
+	}
+	for i, test := range tests {
+		a := app.NewApp("test")
+		a.Log = log.NilLogger{}
+		a.SetMuxer(&recordingMuxer{})
+		a.Handle("GET", test.first, noop)
+		a.Handle("POST", test.second, noop)
+		func() {
+			defer func() {
+				if r := recover(); (r != nil) != test.conflict {
+					t.Errorf("test #%d: expecting conflict %v, got panic %v", i+1, test.conflict, r)
+				}
+			}()
+			a.Handle("GET", test.second, noop)
+		}()
+	}
+}
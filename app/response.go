@@ -0,0 +1,71 @@
+package app
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps the http.ResponseWriter of a request to keep track of the response status.
+// The body of the responses to HEAD requests is discarded, so they can be served by GET handlers.
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+	noBody  bool
+}
+
+func newResponseWriter(w http.ResponseWriter, r *http.Request) *responseWriter {
+	return &responseWriter{ResponseWriter: w, noBody: r.Method == http.MethodHead}
+}
+
+// WriteHeader sends the HTTP response header with the given status code
+func (w *responseWriter) WriteHeader(status int) {
+	if w.written {
+		return
+	}
+	w.status = status
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the data as part of the HTTP response
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.noBody {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends any buffered data to the client, if supported by the wrapped writer
+func (w *responseWriter) Flush() {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, if supported by the wrapped writer.
+// The response is considered written from then on.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, ErrHijackUnsupported
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil {
+		w.status = http.StatusSwitchingProtocols
+		w.written = true
+	}
+	return conn, brw, err
+}
+
+// Unwrap returns the wrapped http.ResponseWriter
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
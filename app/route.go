@@ -0,0 +1,186 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrRouteNotFound is returned when asking for a route name which has not been registered
+	ErrRouteNotFound = errors.New("route not found")
+	// ErrMissingURLParam is returned when building a URL without all the parameters of the route pattern
+	ErrMissingURLParam = errors.New("missing URL parameter")
+)
+
+// Route holds the information of a registered route
+type Route struct {
+	Name    string `json:"name,omitempty"`
+	Host    string `json:"host,omitempty"`
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	Chain   string `json:"chain,omitempty"`
+	// Params holds the constraints declared in the route pattern for its URL parameters
+	Params map[string]string `json:"params,omitempty"`
+
+	app *App
+}
+
+// Named sets the name of the route, so it can be used for reverse routing with App.URL.
+// It panics if the name is already in use by another route.
+func (r *Route) Named(name string) *Route {
+	r.app.Lock()
+	defer r.app.Unlock()
+	if _, exists := r.app.namedRoutes[name]; exists {
+		panic(fmt.Sprintf("route name %s already registered", name))
+	}
+	if r.Name != "" {
+		delete(r.app.namedRoutes, r.Name)
+	}
+	r.Name = name
+	r.app.namedRoutes[name] = r
+	return r
+}
+
+// URL builds a path from the route pattern, replacing its parameters with the given values
+func (r *Route) URL(params URLParams) (string, error) {
+	segments := strings.Split(r.Pattern, "/")
+	for i, seg := range segments {
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+		name := seg[1:]
+		val, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("%w: %s for route %s", ErrMissingURLParam, name, r.Name)
+		}
+		if seg[0] == ':' {
+			val = url.PathEscape(val)
+		}
+		segments[i] = val
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// URL builds the path for the route registered with the given name, using the given parameters
+func (a *App) URL(name string, params URLParams) (string, error) {
+	a.Lock()
+	r, ok := a.namedRoutes[name]
+	a.Unlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+	return r.URL(params)
+}
+
+// Routes returns a copy of the routes registered in the App, sorted by host, pattern and method
+func (a *App) Routes() []Route {
+	a.Lock()
+	defer a.Unlock()
+	routes := make([]Route, len(a.routes))
+	for i, r := range a.routes {
+		routes[i] = *r
+		routes[i].app = nil
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// RoutesHandler encodes the list of the App routes. It is meant to be registered
+// as an introspection endpoint for development, i.e.:
+//
+//	a.Group("", "main").HandleFunc("GET", "/_routes", a.RoutesHandler)
+func (a *App) RoutesHandler(ctx *Context) error {
+	return ctx.Encode(a.Routes())
+}
+
+// AllowedMethods returns the sorted list of methods allowed for the given route pattern of the
+// given host pattern (empty for the App default host). HEAD is allowed for the patterns with
+// a GET route, and OPTIONS for every registered pattern.
+func (a *App) AllowedMethods(host, pattern string) []string {
+	a.Lock()
+	defer a.Unlock()
+	set := make(map[string]bool)
+	for _, r := range a.routes {
+		if r.Host != host || r.Pattern != pattern {
+			continue
+		}
+		set[r.Method] = true
+		if r.Method == http.MethodGet {
+			set[http.MethodHead] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	set[http.MethodOptions] = true
+
+	methods := make([]string, 0, len(set))
+	for m := range set {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// hasPattern reports whether any route has been registered for the given host and pattern.
+// The App must be locked by the caller.
+func (a *App) hasPattern(host, pattern string) bool {
+	for _, r := range a.routes {
+		if r.Host == host && r.Pattern == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// paramConflict returns the registered route with the same host and method as the given one
+// whose pattern only differs by the URL parameters, when any of them has constraints
+func (a *App) paramConflict(r *Route) *Route {
+	shape := paramShape(r.Pattern)
+	for _, other := range a.routes {
+		if other.Host != r.Host || other.Method != r.Method || paramShape(other.Pattern) != shape {
+			continue
+		}
+		if len(r.Params) > 0 || len(other.Params) > 0 {
+			return other
+		}
+	}
+	return nil
+}
+
+// paramShape returns the pattern with the names of its URL parameters removed
+func paramShape(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") {
+			segments[i] = ":"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// allowHandler returns the fallback handler for the requests matching the given pattern, but none
+// of its methods. It answers OPTIONS requests with the allowed methods, and returns ErrMethodNotAllowed
+// for any other method.
+func (a *App) allowHandler(host, pattern string) Handler {
+	return HandlerFunc(func(ctx *Context) error {
+		ctx.Header().Set("Allow", strings.Join(a.AllowedMethods(host, pattern), ", "))
+		if ctx.Request.Method == http.MethodOptions {
+			ctx.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		return ErrMethodNotAllowed
+	})
+}
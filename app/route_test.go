@@ -0,0 +1,132 @@
+package app_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/log"
+)
+
+func TestURL(t *testing.T) {
+	a := app.NewApp("test")
+	a.SetMuxer(&recordingMuxer{})
+	a.AddChain(chain.New(), "main")
+	api := a.Group("/api", "main")
+	noop := func(*app.Context) error { return nil }
+
+	api.HandleFunc("GET", "/todos/:id", noop).Named("todos.retrieve")
+	api.HandleFunc("GET", "/files/*path", noop).Named("files")
+	api.HandleFunc("GET", "/", noop)
+
+	tests := []struct {
+		name     string
+		params   app.URLParams
+		expected string
+		err      error
+	}{
+		{"todos.retrieve", app.URLParams{"id": "42"}, "/api/todos/42", nil},
+		{"todos.retrieve", app.URLParams{"id": "a b/c"}, "/api/todos/a%20b%2Fc", nil},
+		{"files", app.URLParams{"path": "docs/readme.md"}, "/api/files/docs/readme.md", nil},
+		{"todos.retrieve", nil, "", app.ErrMissingURLParam},
+		{"unknown", nil, "", app.ErrRouteNotFound},
+	}
+	for i, test := range tests {
+		actual, err := a.URL(test.name, test.params)
+		if !errors.Is(err, test.err) {
+			t.Errorf("test #%d: expecting error %v, got %v", i+1, test.err, err)
+		}
+		if actual != test.expected {
+			t.Errorf("test #%d: expecting URL %s, got %s", i+1, test.expected, actual)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	a.RoutesHandler(a.NewContextHTTP(rec, httptest.NewRequest("GET", "/_routes", nil)))
+	var routes []app.Route
+	if err := json.NewDecoder(rec.Body).Decode(&routes); err != nil {
+		t.Fatal(err)
+	}
+	expected := []app.Route{
+		{Method: "GET", Pattern: "/api/", Chain: "main"},
+		{Name: "files", Method: "GET", Pattern: "/api/files/*path", Chain: "main"},
+		{Name: "todos.retrieve", Method: "GET", Pattern: "/api/todos/:id", Chain: "main"},
+	}
+	if !reflect.DeepEqual(expected, routes) {
+		t.Errorf("expecting routes %+v, got %+v", expected, routes)
+	}
+}
+
+func TestDuplicateRouteName(t *testing.T) {
+	a := app.NewApp("test")
+	a.SetMuxer(&recordingMuxer{})
+	noop := app.HandlerFunc(func(*app.Context) error { return nil })
+	a.Handle("GET", "/a", noop).Named("dup")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expecting a panic when naming two routes the same")
+		}
+	}()
+	a.Handle("GET", "/b", noop).Named("dup")
+}
+
+type fallbackMuxer struct {
+	recordingMuxer
+	fallbacks map[string]app.Handler
+}
+
+func (m *fallbackMuxer) HandleFallback(path string, h app.Handler) {
+	m.fallbacks[path] = h
+}
+
+func TestMethodFallback(t *testing.T) {
+	var trace []string
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	mux := &fallbackMuxer{fallbacks: make(map[string]app.Handler)}
+	a.SetMuxer(mux)
+	a.AddChain(chain.New(tracer("chain", &trace)), "main")
+	g := a.Group("/todos", "main")
+	noop := func(*app.Context) error { return nil }
+	g.HandleFunc("POST", "", noop)
+	g.HandleFunc("GET", "", noop)
+	g.HandleFunc("PUT", "/:id", noop, tracer("route", &trace))
+	// Routes without a chain are answered through the default one
+	a.Handle("GET", "/health", app.HandlerFunc(noop), tracer("route", &trace))
+
+	if len(mux.fallbacks) != 3 {
+		t.Fatalf("expecting 3 fallbacks, got %d", len(mux.fallbacks))
+	}
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+		trace        []string
+	}{
+		{"DELETE", "/todos", 405, "GET, HEAD, OPTIONS, POST", []string{"chain"}},
+		{"OPTIONS", "/todos", 204, "GET, HEAD, OPTIONS, POST", []string{"chain"}},
+		{"GET", "/todos/:id", 405, "OPTIONS, PUT", []string{"chain", "route"}},
+		{"POST", "/health", 405, "GET, HEAD, OPTIONS", []string{"chain", "route"}},
+		{"OPTIONS", "/health", 204, "GET, HEAD, OPTIONS", []string{"chain", "route"}},
+	}
+	for i, test := range tests {
+		trace = nil
+		rec := httptest.NewRecorder()
+		app.Dispatch(a.NewContextHTTP(rec, httptest.NewRequest(test.method, test.path, nil)), mux.fallbacks[test.path])
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, rec.Code)
+		}
+		if allow := rec.Header().Get("Allow"); allow != test.allow {
+			t.Errorf("test #%d: expecting Allow %s, got %s", i+1, test.allow, allow)
+		}
+		if !reflect.DeepEqual(test.trace, trace) {
+			t.Errorf("test #%d: expecting the fallback to be wrapped with %v, got trace %v", i+1, test.trace, trace)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultAddr is the address used when no one is given in the ServerOptions
+const DefaultAddr = ":8080"
+
+// DefaultShutdownTimeout is the grace period given to in-flight requests when shutting down
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ServerOptions is used to configure the HTTP server(s) of the App
+type ServerOptions struct {
+	// Addrs is a list of TCP addresses the App will listen on.
+	// Default value: [":8080"]
+	Addrs []string
+	// ReadTimeout is the maximum duration for reading the entire request, including the body.
+	// Default value: 0 (no timeout)
+	ReadTimeout time.Duration
+	// WriteTimeout is the maximum duration before timing out writes of the response.
+	// Default value: 0 (no timeout)
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum amount of time to wait for the next request when keep-alives are enabled.
+	// Default value: 0 (ReadTimeout is used)
+	IdleTimeout time.Duration
+	// ShutdownTimeout is the grace period for in-flight requests to finish once shutdown starts.
+	// Default value: 30s
+	ShutdownTimeout time.Duration
+	// StopTimeout is the deadline given to every module implementing Stopper to stop.
+	// Default value: 30s
+	StopTimeout time.Duration
+	// Signals is the list of signals that trigger a graceful shutdown in RunWithOptions.
+	// Default value: [SIGINT, SIGTERM]
+	Signals []os.Signal
+	// TLS, if set, makes the App serve HTTPS (and HTTP/2) on all the addresses
+	TLS *TLSOptions
+}
+
+func (o ServerOptions) withDefaults() ServerOptions {
+	if len(o.Addrs) == 0 {
+		o.Addrs = []string{DefaultAddr}
+	}
+	if o.ShutdownTimeout == 0 {
+		o.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if o.StopTimeout == 0 {
+		o.StopTimeout = DefaultShutdownTimeout
+	}
+	if len(o.Signals) == 0 {
+		o.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	return o
+}
+
+// Run starts the app server with the default options, and blocks until
+// a SIGINT or SIGTERM signal is received or the server fails
+func (a *App) Run() error {
+	return a.RunWithOptions(ServerOptions{})
+}
+
+// RunWithOptions starts the app server with the given options, and blocks until
+// one of the configured signals is received or the server fails
+func (a *App) RunWithOptions(opts ServerOptions) error {
+	opts = opts.withDefaults()
+	ctx, stop := signal.NotifyContext(context.Background(), opts.Signals...)
+	defer stop()
+	return a.Serve(ctx, opts)
+}
+
+// Serve bootstraps and starts the App and serves HTTP requests on the configured addresses, over TLS
+// if configured, until the given context is done or any of the listeners fail. Then, the servers are
+// gracefully shut down, waiting for in-flight requests up to ShutdownTimeout, the modules
+// are stopped and the App is closed.
+func (a *App) Serve(ctx context.Context, opts ServerOptions) error {
+	opts = opts.withDefaults()
+
+	a.Log.Infof("### %s is starting...\n", a.name)
+	if err := a.bootstrap(); err != nil {
+		a.Close()
+		return err
+	}
+
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+	if err := a.start(runCtx); err != nil {
+		cancelRun()
+		a.stop(opts.StopTimeout)
+		a.Close()
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	addrs := opts.Addrs
+	if opts.TLS != nil {
+		var err error
+		if tlsConfig, err = NewTLSConfig(*opts.TLS, a.Log); err != nil {
+			cancelRun()
+			a.stop(opts.StopTimeout)
+			a.Close()
+			return err
+		}
+		if opts.TLS.RedirectAddr != "" {
+			addrs = append(addrs[:len(addrs):len(addrs)], opts.TLS.RedirectAddr)
+		}
+	}
+	listeners, err := listen(addrs)
+	if err != nil {
+		cancelRun()
+		a.stop(opts.StopTimeout)
+		a.Close()
+		return err
+	}
+
+	servers := make([]*http.Server, len(listeners))
+	errc := make(chan error, len(listeners))
+	for i, l := range listeners {
+		srv := a.newServer(opts)
+		servers[i] = srv
+		serve := srv.Serve
+		switch {
+		case i >= len(opts.Addrs):
+			// The redirect listener sends the clients to the port of the first HTTPS listener
+			_, port, _ := net.SplitHostPort(listeners[0].Addr().String())
+			srv.Handler = redirectHTTPS(port)
+			a.Log.Infof("redirecting to HTTPS on %s...\n", l.Addr())
+		case tlsConfig != nil:
+			srv.TLSConfig = tlsConfig
+			if opts.TLS.DisableHTTP2 {
+				srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+			}
+			serve = func(l net.Listener) error { return srv.ServeTLS(l, "", "") }
+			a.Log.Infof("listening on %s (TLS)...\n", l.Addr())
+		default:
+			a.Log.Infof("listening on %s...\n", l.Addr())
+		}
+		go func(l net.Listener) {
+			if err := serve(l); err != nil && err != http.ErrServerClosed {
+				errc <- err
+			}
+		}(l)
+	}
+
+	errs := &ShutdownError{}
+	select {
+	case <-ctx.Done():
+		a.Log.Infof("### %s is shutting down...\n", a.name)
+	case err = <-errc:
+		a.Log.Errorf("server error: %v, shutting down...\n", err)
+		errs.add(err)
+	}
+	cancelRun()
+
+	sctx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		errs.add(srv.Shutdown(sctx))
+	}
+	errs.add(a.stop(opts.StopTimeout))
+	errs.add(a.Close())
+	return errs.errOrNil()
+}
+
+func (a *App) newServer(opts ServerOptions) *http.Server {
+	return &http.Server{
+		Handler:      a,
+		ReadTimeout:  opts.ReadTimeout,
+		WriteTimeout: opts.WriteTimeout,
+		IdleTimeout:  opts.IdleTimeout,
+	}
+}
+
+// listen opens a TCP listener for every address, closing all of them if any fails
+func listen(addrs []string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, addr := range addrs {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
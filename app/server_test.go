@@ -0,0 +1,99 @@
+package app_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/log"
+)
+
+// listenLogger captures the addresses logged by Serve, so the tests can listen on random ports
+type listenLogger struct {
+	log.NilLogger
+	addrs chan string
+}
+
+func newListenLogger() listenLogger {
+	return listenLogger{addrs: make(chan string, 4)}
+}
+
+func (l listenLogger) Infof(format string, v ...interface{}) {
+	if strings.HasPrefix(format, "listening on") || strings.HasPrefix(format, "redirecting to HTTPS on") {
+		l.addrs <- fmt.Sprint(v[0])
+	}
+}
+
+// addr returns the address of the next listener, in the order of the ServerOptions
+func (l listenLogger) addr(t *testing.T) string {
+	t.Helper()
+	select {
+	case addr := <-l.addrs:
+		return addr
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the App to listen")
+		return ""
+	}
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	a := app.NewApp("test")
+	logger := newListenLogger()
+	a.Log = logger
+
+	started := make(chan struct{})
+	a.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- a.Serve(ctx, app.ServerOptions{Addrs: []string{"127.0.0.1:0"}})
+	}()
+	url := "http://" + logger.addr(t) + "/"
+
+	resc := make(chan *http.Response, 1)
+	go func() {
+		var res *http.Response
+		var err error
+		for i := 0; i < 50; i++ {
+			res, err = http.Get(url)
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Errorf("request error: %v", err)
+		}
+		resc <- res
+	}()
+
+	<-started
+	cancel()
+
+	res := <-resc
+	if res == nil || res.StatusCode != http.StatusOK {
+		t.Errorf("expecting in-flight request to finish with status 200, got %+v", res)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("expecting nil error from Serve, got %v", err)
+	}
+}
+
+func TestServeListenError(t *testing.T) {
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+
+	err := a.Serve(context.Background(), app.ServerOptions{Addrs: []string{"invalid-address"}})
+	if err == nil {
+		t.Error("expecting an error for an invalid listen address")
+	}
+}
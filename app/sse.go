@@ -0,0 +1,237 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSSEHeartbeat is the default interval of the heartbeat comments sent by the SSE streams,
+// which keep the connection open through proxies and detect disconnected clients
+const DefaultSSEHeartbeat = 15 * time.Second
+
+var (
+	// ErrStreamingUnsupported is returned by Context.SSE when the response can not be flushed
+	ErrStreamingUnsupported = errors.New("streaming not supported by the response writer")
+	// ErrStreamClosed is returned when sending events to a closed SSE stream
+	ErrStreamClosed = errors.New("SSE stream closed")
+	// ErrInvalidEvent is returned when sending an event whose ID or type contains line breaks
+	ErrInvalidEvent = errors.New("SSE event ID and type can not contain line breaks")
+)
+
+// SSEOptions are used to setup a Server-Sent Events stream
+type SSEOptions struct {
+	// Heartbeat is the interval of the heartbeat comments. Defaults to DefaultSSEHeartbeat;
+	// a negative value disables them.
+	Heartbeat time.Duration
+	// Retry, if set, is sent to the client as the time to wait before reconnecting
+	Retry time.Duration
+}
+
+// Event is a Server-Sent Event
+type Event struct {
+	// ID is the event ID, sent back by the client in the Last-Event-ID header when reconnecting
+	ID string
+	// Event is the event type. Clients get the events without type as "message" events.
+	Event string
+	// Data is the event payload. Strings and byte slices are sent as they are, one data line per
+	// line, and the rest of values are JSON encoded.
+	Data interface{}
+}
+
+// SSEStream is a stream of Server-Sent Events (text/event-stream) to the client.
+// It is safe for concurrent use.
+type SSEStream struct {
+	w           http.ResponseWriter
+	f           http.Flusher
+	lastEventID string
+	done        <-chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	stop   chan struct{}
+}
+
+// SSE starts a Server-Sent Events stream, sending the response headers right away.
+// The stream writes straight to the client, bypassing any buffering of the response by the
+// middlewares, so no other data should be written to the response.
+//
+// The stream is closed when the client disconnects, when Close is called, or when the request
+// Context is closed once the handler returns.
+func (ctx *Context) SSE(options ...SSEOptions) (*SSEStream, error) {
+	var opts SSEOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Heartbeat == 0 {
+		opts.Heartbeat = DefaultSSEHeartbeat
+	}
+
+	var w http.ResponseWriter = ctx.ResponseWriter
+	if ctx.rw != nil {
+		w = ctx.rw
+	}
+	f, ok := flusher(w)
+	if !ok {
+		return nil, ErrStreamingUnsupported
+	}
+
+	s := &SSEStream{
+		w:           w,
+		f:           f,
+		lastEventID: ctx.Request.Header.Get("Last-Event-ID"),
+		done:        ctx.Context().Done(),
+		stop:        make(chan struct{}),
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// Ask reverse proxies (i.e. nginx) not to buffer the stream
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	var err error
+	if opts.Retry > 0 {
+		err = s.write(fmt.Sprintf("retry: %d\n\n", opts.Retry.Milliseconds()))
+	} else {
+		err = s.write("")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.Heartbeat > 0 {
+		go s.heartbeat(opts.Heartbeat)
+	}
+	ctx.streams = append(ctx.streams, s)
+	return s, nil
+}
+
+// flusher returns the http.Flusher of the ResponseWriter, unwrapping it if needed
+func flusher(w http.ResponseWriter) (http.Flusher, bool) {
+	for {
+		if rw, ok := w.(*responseWriter); ok {
+			// responseWriter always implements Flush, check the wrapped writer can
+			if _, ok := flusher(rw.ResponseWriter); !ok {
+				return nil, false
+			}
+			return rw, true
+		}
+		if f, ok := w.(http.Flusher); ok {
+			return f, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// LastEventID returns the ID of the last event received by the client before reconnecting,
+// from the Last-Event-ID request header, so the stream can be resumed after it
+func (s *SSEStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done returns a channel that is closed when the client disconnects
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Send sends an event to the client. It returns ErrStreamClosed if the stream is closed,
+// or the write error if the client is gone, after which the stream is closed.
+func (s *SSEStream) Send(e Event) error {
+	if strings.ContainsAny(e.ID, "\r\n") || strings.ContainsAny(e.Event, "\r\n") {
+		return ErrInvalidEvent
+	}
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Event)
+	}
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		encoded, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+	// A lone CR is a line terminator for the clients too, which would let the data inject fields
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Close closes the stream, stopping the heartbeats. The connection is closed once the handler returns.
+func (s *SSEStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+}
+
+// write writes the data and flushes it to the client
+func (s *SSEStream) write(data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	select {
+	case <-s.done:
+		s.closeLocked()
+		return ErrStreamClosed
+	default:
+	}
+	if data != "" {
+		if _, err := s.w.Write([]byte(data)); err != nil {
+			s.closeLocked()
+			return err
+		}
+	}
+	s.f.Flush()
+	return nil
+}
+
+func (s *SSEStream) closeLocked() {
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+}
+
+// heartbeat sends a comment every interval, until the stream is closed or the client disconnects
+func (s *SSEStream) heartbeat(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-s.stop:
+			return
+		case <-s.done:
+			s.Close()
+			return
+		}
+	}
+}
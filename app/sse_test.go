@@ -0,0 +1,114 @@
+package app_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/buffer"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+	"github.com/syb-devs/goth/log"
+)
+
+func TestSSE(t *testing.T) {
+	sent := make(chan error, 1)
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	a.SetMuxer(stdmux.New(a.NewContextHTTP))
+	a.AddChain(chain.New(buffer.New()), "main")
+	a.Group("", "main").HandleFunc("GET", "/events", func(ctx *app.Context) error {
+		s, err := ctx.SSE(app.SSEOptions{Heartbeat: 10 * time.Millisecond, Retry: time.Second})
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		events := []app.Event{
+			{ID: "1", Data: "resumed after " + s.LastEventID()},
+			{ID: "2", Event: "progress", Data: map[string]int{"done": 50}},
+			{Data: "multi\nline"},
+			{Data: "x\revent: admin\r\ny"},
+		}
+		for _, e := range events {
+			if err := s.Send(e); err != nil {
+				return err
+			}
+		}
+		if err := s.Send(app.Event{Event: "bad\n"}); err != app.ErrInvalidEvent {
+			t.Errorf("expecting error %v, got %v", app.ErrInvalidEvent, err)
+		}
+		// Keep streaming until the client disconnects
+		<-s.Done()
+		time.Sleep(10 * time.Millisecond)
+		sent <- s.Send(app.Event{Data: "too late"})
+		return nil
+	})
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(reqCtx, "GET", srv.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error requesting the events: %v", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expecting content type text/event-stream, got %s", ct)
+	}
+	expected := []string{
+		"retry: 1000", "",
+		"id: 1", "data: resumed after 41", "",
+		"id: 2", "event: progress", `data: {"done":50}`, "",
+		"data: multi", "data: line", "",
+		"data: x", "data: event: admin", "data: y", "",
+	}
+	r := bufio.NewReader(res.Body)
+	for i, want := range expected {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("line #%d: error reading the stream: %v", i+1, err)
+		}
+		if got := strings.TrimSuffix(line, "\n"); got != want {
+			t.Errorf("line #%d: expecting %q, got %q", i+1, want, got)
+		}
+	}
+	// The stream is kept alive with heartbeat comments
+	if line, err := r.ReadString('\n'); err != nil || line != ": heartbeat\n" {
+		t.Errorf("expecting a heartbeat comment, got %q (error %v)", line, err)
+	}
+
+	cancel()
+	select {
+	case err := <-sent:
+		if err != app.ErrStreamClosed {
+			t.Errorf("expecting error %v after the client disconnected, got %v", app.ErrStreamClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expecting the handler to detect the client disconnection")
+	}
+}
+
+func TestSSEUnsupported(t *testing.T) {
+	a := app.NewApp("test")
+	ctx := a.NewContextHTTP(noFlushWriter{httptest.NewRecorder()}, httptest.NewRequest("GET", "/", nil))
+	if _, err := ctx.SSE(); err != app.ErrStreamingUnsupported {
+		t.Errorf("expecting error %v, got %v", app.ErrStreamingUnsupported, err)
+	}
+}
+
+// noFlushWriter hides the http.Flusher implementation of the wrapped ResponseWriter
+type noFlushWriter struct {
+	w *httptest.ResponseRecorder
+}
+
+func (w noFlushWriter) Header() http.Header         { return w.w.Header() }
+func (w noFlushWriter) Write(b []byte) (int, error) { return w.w.Write(b) }
+func (w noFlushWriter) WriteHeader(status int)      { w.w.WriteHeader(status) }
@@ -0,0 +1,229 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syb-devs/goth/log"
+)
+
+// DefaultCertReloadInterval is the default minimum time between checks of the TLS files for changes
+const DefaultCertReloadInterval = time.Minute
+
+// ErrNoCertificate is returned when the TLSOptions lack the certificate or key file
+var ErrNoCertificate = errors.New("TLS certificate and key files are required")
+
+// TLSOptions is used to serve the App over HTTPS
+type TLSOptions struct {
+	// CertFile is the path of the PEM encoded certificate, followed by any intermediate ones
+	CertFile string
+	// KeyFile is the path of the PEM encoded private key of the certificate
+	KeyFile string
+	// MinVersion is the minimum TLS version accepted.
+	// Default value: tls.VersionTLS12
+	MinVersion uint16
+	// CipherSuites is the list of cipher suites enabled for TLS 1.2 and earlier; the TLS 1.3 ones
+	// are not configurable. They must include an ECDHE AES-GCM suite to serve HTTP/2.
+	// Default value: the Go secure defaults
+	CipherSuites []uint16
+	// ClientCAFile is the path of the PEM encoded CA certificates used to verify the certificates
+	// of the clients (mTLS)
+	ClientCAFile string
+	// ClientAuth is the policy for the certificates of the clients.
+	// Default value: tls.RequireAndVerifyClientCert if ClientCAFile is set, tls.NoClientCert otherwise
+	ClientAuth tls.ClientAuthType
+	// ReloadInterval is the minimum time between checks of the files for changes, which are made
+	// on the TLS handshakes. The changed files are reloaded without restarting the server.
+	// Default value: 1m. A negative value disables the reloading.
+	ReloadInterval time.Duration
+	// DisableHTTP2 serves only HTTP/1.1 over TLS
+	DisableHTTP2 bool
+	// RedirectAddr, if set, is the TCP address of a plain HTTP listener redirecting all the
+	// requests to HTTPS, i.e. ":80"
+	RedirectAddr string
+}
+
+// NewTLSConfig returns a tls.Config for serving with the given options, whose certificate and
+// client CAs are reloaded from disk when the files change. The reloads and their errors are
+// logged to the given logger, if not nil.
+func NewTLSConfig(opts TLSOptions, logger log.Logger) (*tls.Config, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, ErrNoCertificate
+	}
+	if opts.MinVersion == 0 {
+		opts.MinVersion = tls.VersionTLS12
+	}
+	if opts.ClientAuth == tls.NoClientCert && opts.ClientCAFile != "" {
+		opts.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if opts.ReloadInterval == 0 {
+		opts.ReloadInterval = DefaultCertReloadInterval
+	}
+
+	if logger == nil {
+		logger = log.NilLogger{}
+	}
+
+	r := &certReloader{opts: opts, log: logger}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:     opts.MinVersion,
+		CipherSuites:   opts.CipherSuites,
+		ClientAuth:     opts.ClientAuth,
+		GetCertificate: r.getCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if opts.DisableHTTP2 {
+		cfg.NextProtos = []string{"http/1.1"}
+	}
+	if opts.ClientCAFile != "" {
+		base := cfg.Clone()
+		cfg.ClientCAs = r.clientCAs
+		// The client CAs are part of the config, so serve a fresh one for each handshake
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.reloadIfChanged()
+			c := base.Clone()
+			r.mu.RLock()
+			c.ClientCAs = r.clientCAs
+			r.mu.RUnlock()
+			return c, nil
+		}
+	}
+	return cfg, nil
+}
+
+// certReloader holds the certificate and client CAs loaded from the TLS files,
+// reloading them when their modification time changes
+type certReloader struct {
+	opts TLSOptions
+	log  log.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  []time.Time
+
+	checkMu sync.Mutex
+	checked time.Time
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	return files
+}
+
+// load reads and parses the TLS files, replacing the current certificate and client CAs on success
+func (r *certReloader) load() error {
+	modTimes, err := statFiles(r.files())
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("error loading TLS certificate: %v", err)
+	}
+	var pool *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("error loading TLS client CAs: %v", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("error loading TLS client CAs: no certificates found in %s", r.opts.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCAs = pool
+	r.modTimes = modTimes
+	return nil
+}
+
+// reloadIfChanged reloads the files if the reload interval has passed since the last check and any
+// of them changed. On failure, the error is logged and the loaded certificate kept.
+func (r *certReloader) reloadIfChanged() {
+	if r.opts.ReloadInterval < 0 {
+		return
+	}
+	r.checkMu.Lock()
+	defer r.checkMu.Unlock()
+	if time.Since(r.checked) < r.opts.ReloadInterval {
+		return
+	}
+	r.checked = time.Now()
+
+	modTimes, err := statFiles(r.files())
+	if err != nil {
+		r.log.Errorf("error checking TLS files for changes: %v", err)
+		return
+	}
+	r.mu.RLock()
+	changed := false
+	for i, t := range modTimes {
+		changed = changed || !t.Equal(r.modTimes[i])
+	}
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+	if err := r.load(); err != nil {
+		r.log.Errorf("error reloading TLS files, keeping the current certificate: %v", err)
+		return
+	}
+	r.log.Infof("TLS certificate reloaded from %s", r.opts.CertFile)
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.reloadIfChanged()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func statFiles(files []string) ([]time.Time, error) {
+	modTimes := make([]time.Time, len(files))
+	for i, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[i] = fi.ModTime()
+	}
+	return modTimes, nil
+}
+
+// redirectHTTPS returns a handler redirecting the requests to the same URL over HTTPS, on the given port
+func redirectHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = host
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,220 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/log"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newCert creates a certificate signed by the parent one, or a self-signed CA if nil
+func newCert(t *testing.T, name string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write writes the certificate and key PEM files, returning their paths
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	writeTLSFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}))
+	writeTLSFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// writeTLSFile writes the file with a modification time in the future, so changes are always detected
+func writeTLSFile(t *testing.T, path string, data []byte) {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "ca", nil, x509.ExtKeyUsageAny)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newCert(t, "server", ca, x509.ExtKeyUsageServerAuth).write(t, dir, "server")
+	client := newCert(t, "client", ca, x509.ExtKeyUsageClientAuth)
+
+	a := app.NewApp("test")
+	logger := newListenLogger()
+	a.Log = logger
+	a.SetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto + " " + r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- a.Serve(ctx, app.ServerOptions{
+			Addrs: []string{"127.0.0.1:0"},
+			TLS: &app.TLSOptions{
+				CertFile:     certFile,
+				KeyFile:      keyFile,
+				ClientCAFile: caFile,
+				RedirectAddr: "127.0.0.1:0",
+			},
+		})
+	}()
+	defer func() {
+		cancel()
+		if err := <-errc; err != nil {
+			t.Errorf("expecting nil error from Serve, got %v", err)
+		}
+	}()
+	addr, redirectAddr := logger.addr(t), logger.addr(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+				ForceAttemptHTTP2: true,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+	}
+
+	var res *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if res, err = newClient(client.tlsCertificate()).Get("https://" + addr + "/"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	body := make([]byte, 64)
+	n, _ := res.Body.Read(body)
+	res.Body.Close()
+	if got := string(body[:n]); got != "HTTP/2.0 client" {
+		t.Errorf("expecting an HTTP/2 request with the client certificate, got %q", got)
+	}
+
+	if _, err := newClient().Get("https://" + addr + "/"); err == nil {
+		t.Error("expecting an error connecting without client certificate")
+	}
+
+	res, err = newClient().Get("http://" + redirectAddr + "/todos?page=2")
+	if err != nil {
+		t.Fatalf("redirect request error: %v", err)
+	}
+	res.Body.Close()
+	if loc := res.Header.Get("Location"); res.StatusCode != http.StatusPermanentRedirect || loc != "https://"+addr+"/todos?page=2" {
+		t.Errorf("expecting a redirect to HTTPS, got %d %s", res.StatusCode, loc)
+	}
+}
+
+func TestTLSConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "ca", nil, x509.ExtKeyUsageAny)
+	first := newCert(t, "first", ca, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := first.write(t, dir, "server")
+
+	var logged bytes.Buffer
+	logger := log.New(&logged)
+	logger.SetColoring(false)
+	cfg, err := app.NewTLSConfig(app.TLSOptions{CertFile: certFile, KeyFile: keyFile, ReloadInterval: time.Nanosecond}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.NextProtos[0] != "h2" {
+		t.Errorf("expecting TLS 1.2 and HTTP/2 by default, got version %x and protocols %v", cfg.MinVersion, cfg.NextProtos)
+	}
+
+	expectCert := func(expected string) {
+		t.Helper()
+		cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := x509.ParseCertificate(cert.Certificate[0]); got.Subject.CommonName != expected {
+			t.Errorf("expecting certificate %s, got %s", expected, got.Subject.CommonName)
+		}
+	}
+	expectCert("first")
+
+	time.Sleep(time.Millisecond)
+	newCert(t, "second", ca, x509.ExtKeyUsageServerAuth).write(t, dir, "server")
+	expectCert("second")
+	if !strings.Contains(logged.String(), "TLS certificate reloaded") {
+		t.Errorf("expecting the reload to be logged, got %q", logged.String())
+	}
+
+	// A broken certificate is not loaded
+	time.Sleep(time.Millisecond)
+	writeTLSFile(t, certFile, []byte("not a certificate"))
+	expectCert("second")
+	if !strings.Contains(logged.String(), "error reloading TLS files") {
+		t.Errorf("expecting the reload error to be logged, got %q", logged.String())
+	}
+
+	if _, err := app.NewTLSConfig(app.TLSOptions{CertFile: certFile, KeyFile: keyFile}, nil); err == nil {
+		t.Error("expecting an error loading a broken certificate")
+	}
+	if _, err := app.NewTLSConfig(app.TLSOptions{}, nil); err != app.ErrNoCertificate {
+		t.Errorf("expecting error %v, got %v", app.ErrNoCertificate, err)
+	}
+}
@@ -68,7 +68,7 @@ func NewTokenProcessorFunc(options ...TokenProcessorOptions) func(ctx *app.Conte
 			return ErrInvalidUserID
 		}
 		user := ctx.App.DB.CreateResource(userType).(database.Resource)
-		err := ctx.App.DB.Get(userID, user)
+		err := ctx.DB.Get(ctx.Context(), userID, user)
 		if err != nil {
 			return err
 		}
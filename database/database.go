@@ -1,5 +1,13 @@
 package database
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned by repositories when no resource matches a query
+var ErrNotFound = errors.New("resource not found")
+
 // ConnectionParams is a map which stores database connection parameters
 type ConnectionParams map[string]interface{}
 
@@ -11,16 +19,25 @@ type Connection interface {
 	Interface() interface{}
 }
 
-// Repository is an interface implemented by database repositories
+// Pinger is implemented by the connections which can check that the database is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Repository is an interface implemented by database repositories.
+// Operations should be abandoned, returning the context error, when the given context is done.
 type Repository interface {
-	Insert(d Resource) error
-	Update(d Resource) error
-	Delete(d Resource) error
-	Get(ID interface{}, dest Resource) error
-	FindOne(dest Resource, q Query) error
-	FindMany(dest ResourceList, q Query) error
-	FetchRelated(d Resource, relations ...string) error
+	Insert(ctx context.Context, d Resource) error
+	Update(ctx context.Context, d Resource) error
+	Delete(ctx context.Context, d Resource) error
+	Get(ctx context.Context, ID interface{}, dest Resource) error
+	FindOne(ctx context.Context, dest Resource, q Query) error
+	FindMany(ctx context.Context, dest ResourceList, q Query) error
+	FetchRelated(ctx context.Context, d Resource, relations ...string) error
 }
 
+// RepositoryFactory is a function that returns a Repository bound to the given Connection
+type RepositoryFactory func(Connection) Repository
+
 // Dict is an alias to map[string]interface{}
 type Dict map[string]interface{}
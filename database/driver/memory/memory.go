@@ -0,0 +1,79 @@
+// Package memory implements an in-memory database driver, meant for tests and prototypes.
+//
+// Resources are stored BSON encoded, as the MongoDB driver does, so the same resource
+// types (i.e. embedding mongodb.Resource) can be used with both drivers.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/syb-devs/goth/database"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Compile-time interface check
+var _ database.Connection = (*Conn)(nil)
+var _ database.Pinger = (*Conn)(nil)
+
+// Conn represents a connection to an in-memory database.
+// The copies of a connection share the same data.
+type Conn struct {
+	db     *db
+	resMap *database.ResourceMap
+}
+
+// db holds the documents of every collection, in insertion order
+type db struct {
+	sync.RWMutex
+	cols map[string][]bson.M
+}
+
+// NewConnection returns a connection to a new, empty, in-memory database
+func NewConnection(resMap *database.ResourceMap) *Conn {
+	return &Conn{
+		db:     &db{cols: make(map[string][]bson.M)},
+		resMap: resMap,
+	}
+}
+
+// Connect does nothing, as there is no server to connect to
+func (c *Conn) Connect(ps database.ConnectionParams) error {
+	return nil
+}
+
+// Copy returns a copy of the connection, sharing its data
+func (c *Conn) Copy() database.Connection {
+	return &Conn{
+		db:     c.db,
+		resMap: c.resMap,
+	}
+}
+
+// Close does nothing, as the data must outlive the copies of the connection
+func (c *Conn) Close() error {
+	return nil
+}
+
+// Ping always succeeds, unless the given context is done
+func (c *Conn) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Reset removes all the resources from the database
+func (c *Conn) Reset() {
+	c.db.Lock()
+	defer c.db.Unlock()
+	c.db.cols = make(map[string][]bson.M)
+}
+
+// Map returs the resource map associated with the connection
+func (c *Conn) Map() *database.ResourceMap {
+	return c.resMap
+}
+
+// Interface returns the connection itself, as there is no underlying driver
+func (c *Conn) Interface() interface{} {
+	return c
+}
@@ -0,0 +1,529 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syb-devs/goth/database"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	// ErrDuplicateID is returned when inserting a resource with the ID of an existing one
+	ErrDuplicateID = errors.New("duplicate resource ID")
+	// ErrUnsupportedQuery is returned for the queries using operators not supported by the driver
+	ErrUnsupportedQuery = errors.New("unsupported query operator")
+)
+
+var _ database.Repository = (*Repository)(nil)
+
+// Repository implements the Repository interface for the in-memory database.
+// Queries are documents in the MongoDB format, supporting field equality (matching the
+// elements of arrays too), dotted field paths, and the $eq, $ne, $in, $nin, $gt, $gte,
+// $lt and $lte operators.
+type Repository struct {
+	Conn        *Conn
+	IDGenerator func() interface{}
+}
+
+// NewRepository returns an in-memory repository using the given connection
+func NewRepository(conn *Conn) *Repository {
+	return &Repository{
+		Conn:        conn,
+		IDGenerator: func() interface{} { return bson.NewObjectId() },
+	}
+}
+
+// RepositoryFactory returns a Repository for the given in-memory connection.
+// It can be used as the App database.RepositoryFactory.
+func RepositoryFactory(conn database.Connection) database.Repository {
+	return NewRepository(conn.(*Conn))
+}
+
+// Insert inserts the resource in the corresponding collection
+func (r *Repository) Insert(ctx context.Context, d database.Resource) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	colName, err := r.Conn.Map().ColFor(d)
+	if err != nil {
+		return err
+	}
+	r.touch(d)
+	if r.IDGenerator != nil && isZero(d.GetID()) {
+		d.SetID(r.IDGenerator())
+	}
+	doc, err := toDoc(d)
+	if err != nil {
+		return err
+	}
+
+	r.Conn.db.Lock()
+	defer r.Conn.db.Unlock()
+	return r.Conn.db.insert(colName, doc)
+}
+
+// Update updates the resource in the database
+func (r *Repository) Update(ctx context.Context, d database.Resource) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	colName, err := r.Conn.Map().ColFor(d)
+	if err != nil {
+		return err
+	}
+	r.touch(d)
+	doc, err := toDoc(d)
+	if err != nil {
+		return err
+	}
+
+	r.Conn.db.Lock()
+	defer r.Conn.db.Unlock()
+	i := r.Conn.db.indexOf(colName, doc["_id"])
+	if i == -1 {
+		return database.ErrNotFound
+	}
+	r.Conn.db.cols[colName][i] = doc
+	return nil
+}
+
+func (r *Repository) touch(d database.Resource) {
+	if t, ok := d.(database.Toucher); ok {
+		t.Touch()
+	}
+}
+
+// Delete deletes the resource from the database
+func (r *Repository) Delete(ctx context.Context, d database.Resource) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	colName, err := r.Conn.Map().ColFor(d)
+	if err != nil {
+		return err
+	}
+	delColName, err := r.Conn.Map().DeletedColFor(d)
+	if err != nil {
+		return err
+	}
+
+	r.Conn.db.Lock()
+	defer r.Conn.db.Unlock()
+	i := r.Conn.db.indexOf(colName, normalize(d.GetID()))
+	if i == -1 {
+		return database.ErrNotFound
+	}
+	if delColName != "" {
+		if td, ok := d.(database.SoftDeletable); ok {
+			// Logic delete
+			td.MarkDeleted()
+		}
+		doc, err := toDoc(d)
+		if err != nil {
+			return err
+		}
+		if err := r.Conn.db.insert(delColName, doc); err != nil {
+			return err
+		}
+	}
+	docs := r.Conn.db.cols[colName]
+	r.Conn.db.cols[colName] = append(docs[:i:i], docs[i+1:]...)
+	return nil
+}
+
+// Get retrieves a resource from the database and stores in the given type
+func (r *Repository) Get(ctx context.Context, ID interface{}, dest database.Resource) error {
+	if idstr, ok := ID.(string); ok {
+		// A malformed ID can not match any resource
+		if !bson.IsObjectIdHex(idstr) {
+			return database.ErrNotFound
+		}
+		ID = bson.ObjectIdHex(idstr)
+	}
+	return r.FindOne(ctx, dest, database.NewQ(bson.M{"_id": ID}))
+}
+
+// FindOne runs the given query, retrieving a single resource and stores in the given type
+func (r *Repository) FindOne(ctx context.Context, dest database.Resource, query database.Query) error {
+	query.Limit = 1
+	docs, err := r.query(ctx, dest, query)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return database.ErrNotFound
+	}
+	return fromDoc(docs[0], dest)
+}
+
+// FindMany runs the given query, retrieving all matching resources and stores in the given type slice
+func (r *Repository) FindMany(ctx context.Context, dest database.ResourceList, query database.Query) error {
+	docs, err := r.query(ctx, dest, query)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return database.ErrSliceExpected
+	}
+	list := reflect.MakeSlice(v.Elem().Type(), 0, len(docs))
+	elemType := list.Type().Elem()
+	for _, doc := range docs {
+		var elem reflect.Value
+		if elemType.Kind() == reflect.Ptr {
+			elem = reflect.New(elemType.Elem())
+		} else {
+			elem = reflect.New(elemType)
+		}
+		if err := fromDoc(doc, elem.Interface()); err != nil {
+			return err
+		}
+		if elemType.Kind() != reflect.Ptr {
+			elem = elem.Elem()
+		}
+		list = reflect.Append(list, elem)
+	}
+	v.Elem().Set(list)
+	return nil
+}
+
+// query returns the documents of the collection for the destination type matching the query
+func (r *Repository) query(ctx context.Context, dest interface{}, query database.Query) ([]bson.M, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	colName, err := r.Conn.Map().ColFor(dest)
+	if err != nil {
+		return nil, err
+	}
+	where := bson.M{}
+	if query.Where != nil {
+		if where, err = toDoc(query.Where); err != nil {
+			return nil, err
+		}
+	}
+
+	r.Conn.db.RLock()
+	var docs []bson.M
+	for _, doc := range r.Conn.db.cols[colName] {
+		ok, err := match(doc, where)
+		if err != nil {
+			r.Conn.db.RUnlock()
+			return nil, err
+		}
+		if ok {
+			docs = append(docs, doc)
+		}
+	}
+	r.Conn.db.RUnlock()
+
+	sortDocs(docs, query.Sort)
+	// Negative numbers are accepted
+	if skip := abs(query.Skip); skip < len(docs) {
+		docs = docs[skip:]
+	} else {
+		docs = nil
+	}
+	// Negative numbers are accepted
+	if limit := abs(query.Limit); limit != 0 && limit < len(docs) {
+		docs = docs[:limit]
+	}
+	return docs, nil
+}
+
+// FetchRelated fetchs resouces related to the given resource
+func (r *Repository) FetchRelated(ctx context.Context, source database.Resource, relations ...string) error {
+	for _, relation := range relations {
+		rel, err := r.Conn.Map().Relationship(source, relation)
+		if err != nil {
+			return err
+		}
+		refs := database.RelationshipRefs(rel, source)
+		switch rel.Kind {
+		case database.HasOne, database.HasZeroOne:
+			if rel.Kind == database.HasZeroOne {
+				v := reflect.ValueOf(refs)
+				if v.IsNil() {
+					continue
+				}
+				refs = v.Elem().Interface()
+			}
+			dest := database.RelationshipTarget(rel, source)
+			err = r.Get(ctx, refs, dest.(database.Resource))
+			if err != nil {
+				return err
+			}
+		case database.HasMany:
+			if reflect.ValueOf(refs).Len() == 0 {
+				continue
+			}
+			where := bson.M{"_id": bson.M{"$in": refs}}
+			dest := database.RelationshipTarget(rel, source)
+			err = r.FindMany(ctx, dest, database.NewQ(where))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// insert appends a document to a collection, unless its ID is already in use
+func (db *db) insert(colName string, doc bson.M) error {
+	if db.indexOf(colName, doc["_id"]) != -1 {
+		return ErrDuplicateID
+	}
+	db.cols[colName] = append(db.cols[colName], doc)
+	return nil
+}
+
+// indexOf returns the position of the document with the given ID in a collection, or -1
+func (db *db) indexOf(colName string, ID interface{}) int {
+	for i, doc := range db.cols[colName] {
+		if equal(doc["_id"], ID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// toDoc returns the BSON document for the given value, so it does not share any memory with it
+func toDoc(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	return doc, bson.Unmarshal(data, &doc)
+}
+
+// fromDoc stores the given BSON document in the destination value
+func fromDoc(doc bson.M, dest interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, dest)
+}
+
+// normalize returns a value as it is found in the stored documents
+func normalize(v interface{}) interface{} {
+	doc, err := toDoc(bson.M{"v": v})
+	if err != nil {
+		return v
+	}
+	return doc["v"]
+}
+
+func isZero(v interface{}) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// lookup returns the value of a field of the document, which can be a dotted path to a nested field
+func lookup(doc bson.M, path string) interface{} {
+	var v interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		sub, ok := v.(bson.M)
+		if !ok {
+			return nil
+		}
+		v = sub[key]
+	}
+	return v
+}
+
+// match checks if the document satisfies the conditions of the query
+func match(doc bson.M, where bson.M) (bool, error) {
+	for path, cond := range where {
+		ok, err := matchValue(lookup(doc, path), cond)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchValue checks if a field value satisfies a condition, which is either the expected value
+// or a document of operators
+func matchValue(value, cond interface{}) (bool, error) {
+	ops, ok := cond.(bson.M)
+	if !ok || len(ops) == 0 {
+		return equal(value, cond), nil
+	}
+	for op := range ops {
+		if !strings.HasPrefix(op, "$") {
+			return equal(value, cond), nil
+		}
+	}
+	for op, arg := range ops {
+		ok, err := matchOperator(value, op, arg)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(value interface{}, op string, arg interface{}) (bool, error) {
+	switch op {
+	case "$eq":
+		return equal(value, arg), nil
+	case "$ne":
+		return !equal(value, arg), nil
+	case "$in", "$nin":
+		list, ok := arg.([]interface{})
+		if !ok {
+			return false, ErrUnsupportedQuery
+		}
+		found := false
+		for _, item := range list {
+			if equal(value, item) {
+				found = true
+				break
+			}
+		}
+		return found == (op == "$in"), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		c, ok := compare(value, arg)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case "$gt":
+			return c > 0, nil
+		case "$gte":
+			return c >= 0, nil
+		case "$lt":
+			return c < 0, nil
+		default:
+			return c <= 0, nil
+		}
+	default:
+		return false, ErrUnsupportedQuery
+	}
+}
+
+// equal checks if a field value is equal to the given one. Like in MongoDB, an array
+// field is also equal to any of its elements.
+func equal(value, other interface{}) bool {
+	if c, ok := compare(value, other); ok {
+		return c == 0
+	}
+	if list, ok := value.([]interface{}); ok {
+		if _, otherList := other.([]interface{}); !otherList {
+			for _, item := range list {
+				if equal(item, other) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return reflect.DeepEqual(value, other)
+}
+
+// compare compares two values of the same kind: numbers, strings, ObjectIds, times or booleans.
+// It returns false if they are not comparable.
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	case bson.ObjectId:
+		if b, ok := b.(bson.ObjectId); ok {
+			return strings.Compare(string(a), string(b)), true
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			switch {
+			case a.Before(b):
+				return -1, true
+			case a.After(b):
+				return 1, true
+			}
+			return 0, true
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0, true
+			case !a:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// sortDocs sorts the documents by the given fields, prefixed with - for descending order.
+// Missing fields go first.
+func sortDocs(docs []bson.M, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, field := range fields {
+			desc := strings.HasPrefix(field, "-")
+			path := strings.TrimLeft(field, "+-")
+			a, b := lookup(docs[i], path), lookup(docs[j], path)
+			var c int
+			switch {
+			case a == nil && b == nil:
+			case a == nil:
+				c = -1
+			case b == nil:
+				c = 1
+			default:
+				c, _ = compare(a, b)
+			}
+			if c != 0 {
+				return c < 0 != desc
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,194 @@
+package memory_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/syb-devs/goth/database"
+	"github.com/syb-devs/goth/database/driver/memory"
+	"github.com/syb-devs/goth/database/driver/mongodb"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type User struct {
+	mongodb.Resource `bson:",inline" json:",inline"`
+	Name             string          `bson:"name" json:"name"`
+	TodoIDs          []bson.ObjectId `bson:"todoIds" json:"-" rel:"todos,Todos"`
+	Todos            []*Todo         `bson:"-" json:"todos"`
+	ProfileID        *bson.ObjectId  `bson:"profileId" json:"-" rel:"profile,Profile"`
+	Profile          *Profile        `bson:"-" json:"profile"`
+}
+
+type Profile struct {
+	mongodb.Resource `bson:",inline" json:",inline"`
+	Twitter          string `bson:"twitter" json:"twitter"`
+}
+
+type Todo struct {
+	mongodb.Resource `bson:",inline" json:",inline"`
+	Title            string   `bson:"title" json:"title"`
+	Priority         int      `bson:"priority" json:"priority"`
+	Tags             []string `bson:"tags" json:"tags"`
+}
+
+// DeletedTodo is used for reading the archived todos
+type DeletedTodo Todo
+
+func newRepository() *memory.Repository {
+	dbmap := database.NewResourceMap()
+	dbmap.RegisterResource(User{}, "users", "")
+	dbmap.RegisterResource(Profile{}, "profiles", "")
+	dbmap.RegisterResource(Todo{}, "todos", "deleted_todos")
+	dbmap.RegisterResource(DeletedTodo{}, "deleted_todos", "")
+	return memory.NewRepository(memory.NewConnection(dbmap))
+}
+
+func insert(t *testing.T, r database.Repository, res ...database.Resource) {
+	for _, d := range res {
+		if err := r.Insert(context.Background(), d); err != nil {
+			t.Fatalf("error inserting %T: %v", d, err)
+		}
+	}
+}
+
+func TestCRUD(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository()
+
+	todo := &Todo{Title: "write tests"}
+	insert(t, r, todo)
+	if !todo.ID.Valid() {
+		t.Fatalf("expecting a generated ID, got %q", todo.ID)
+	}
+	if err := r.Insert(ctx, todo); err != memory.ErrDuplicateID {
+		t.Errorf("expecting error %v, got %v", memory.ErrDuplicateID, err)
+	}
+
+	todo.Title = "changed, not updated"
+	got := &Todo{}
+	if err := r.Get(ctx, todo.ID.Hex(), got); err != nil {
+		t.Fatalf("error getting todo: %v", err)
+	}
+	if got.Title != "write tests" {
+		t.Errorf("expecting title %q, got %q", "write tests", got.Title)
+	}
+
+	got.Title = "write more tests"
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatalf("error updating todo: %v", err)
+	}
+	if err := r.Get(ctx, todo.ID, todo); err != nil || todo.Title != "write more tests" {
+		t.Errorf("expecting updated title %q, got %q (error %v)", "write more tests", todo.Title, err)
+	}
+
+	if err := r.Delete(ctx, todo); err != nil {
+		t.Fatalf("error deleting todo: %v", err)
+	}
+	if err := r.Get(ctx, todo.ID, &Todo{}); err != database.ErrNotFound {
+		t.Errorf("expecting error %v, got %v", database.ErrNotFound, err)
+	}
+	if err := r.Delete(ctx, todo); err != database.ErrNotFound {
+		t.Errorf("expecting error %v, got %v", database.ErrNotFound, err)
+	}
+	if err := r.Update(ctx, todo); err != database.ErrNotFound {
+		t.Errorf("expecting error %v, got %v", database.ErrNotFound, err)
+	}
+	if err := r.Get(ctx, "not-an-id", &Todo{}); err != database.ErrNotFound {
+		t.Errorf("expecting error %v, got %v", database.ErrNotFound, err)
+	}
+
+	var deleted []DeletedTodo
+	if err := r.FindMany(ctx, &deleted, database.NewQ(nil)); err != nil || len(deleted) != 1 || deleted[0].DeletedAt.IsZero() {
+		t.Errorf("expecting the deleted todo to be archived, got %+v (error %v)", deleted, err)
+	}
+}
+
+func TestFindMany(t *testing.T) {
+	r := newRepository()
+	insert(t, r,
+		&Todo{Title: "a", Priority: 2, Tags: []string{"home"}},
+		&Todo{Title: "b", Priority: 1, Tags: []string{"work", "urgent"}},
+		&Todo{Title: "c", Priority: 3, Tags: []string{"work"}},
+		&Todo{Title: "d", Priority: 1},
+	)
+
+	tests := []struct {
+		query  database.Query
+		titles []string
+	}{
+		{database.NewQ(nil), []string{"a", "b", "c", "d"}},
+		{database.Query{}, []string{"a", "b", "c", "d"}},
+		{database.NewQ(database.Dict{"priority": 1}), []string{"b", "d"}},
+		{database.NewQ(bson.M{"tags": "work"}), []string{"b", "c"}},
+		{database.NewQ(bson.M{"priority": bson.M{"$gte": 2}}, "-priority"), []string{"c", "a"}},
+		{database.NewQ(bson.M{"title": bson.M{"$in": []string{"a", "d", "z"}}}), []string{"a", "d"}},
+		{database.NewQ(bson.M{"title": bson.M{"$nin": []string{"a", "d"}, "$ne": "b"}}), []string{"c"}},
+		{database.NewQ(nil, "priority", "-title"), []string{"d", "b", "a", "c"}},
+		{database.NewQuery(nil, 2, 1, "title"), []string{"b", "c"}},
+		{database.NewQuery(nil, -1, 0, "-title"), []string{"d"}},
+		{database.NewQuery(nil, 0, 10), nil},
+	}
+
+	for i, test := range tests {
+		var todos []*Todo
+		if err := r.FindMany(context.Background(), &todos, test.query); err != nil {
+			t.Errorf("test #%d: unexpected error %v", i+1, err)
+			continue
+		}
+		var titles []string
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		if !reflect.DeepEqual(titles, test.titles) {
+			t.Errorf("test #%d: expecting titles %v, got %v", i+1, test.titles, titles)
+		}
+	}
+
+	var todos []Todo
+	err := r.FindMany(context.Background(), &todos, database.NewQ(bson.M{"title": bson.M{"$regex": "a"}}))
+	if err != memory.ErrUnsupportedQuery {
+		t.Errorf("expecting error %v, got %v", memory.ErrUnsupportedQuery, err)
+	}
+	todo := &Todo{}
+	if err := r.FindOne(context.Background(), todo, database.NewQ(nil, "-priority")); err != nil || todo.Title != "c" {
+		t.Errorf("expecting todo %q, got %q (error %v)", "c", todo.Title, err)
+	}
+}
+
+func TestFetchRelated(t *testing.T) {
+	r := newRepository()
+	todo1, todo2, profile := &Todo{Title: "a"}, &Todo{Title: "b"}, &Profile{Twitter: "@goth"}
+	insert(t, r, todo1, todo2, profile)
+	u := &User{Name: "john", TodoIDs: []bson.ObjectId{todo2.ID, todo1.ID}, ProfileID: &profile.ID}
+	insert(t, r, u)
+
+	got := &User{}
+	if err := r.Get(context.Background(), u.ID, got); err != nil {
+		t.Fatalf("error getting user: %v", err)
+	}
+	if err := r.FetchRelated(context.Background(), got, "todos", "profile"); err != nil {
+		t.Fatalf("error fetching related resources: %v", err)
+	}
+	if len(got.Todos) != 2 || got.Profile == nil || got.Profile.Twitter != "@goth" {
+		t.Errorf("expecting 2 todos and the profile, got %+v", got)
+	}
+
+	empty := &User{Name: "jane"}
+	if err := r.FetchRelated(context.Background(), empty, "todos", "profile"); err != nil || empty.Todos != nil || empty.Profile != nil {
+		t.Errorf("expecting no related resources, got %+v (error %v)", empty, err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	r := newRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Insert(ctx, &Todo{}); err != context.Canceled {
+		t.Errorf("expecting error %v, got %v", context.Canceled, err)
+	}
+	if err := r.Conn.Ping(ctx); err != context.Canceled {
+		t.Errorf("expecting error %v, got %v", context.Canceled, err)
+	}
+}
@@ -1,7 +1,9 @@
 package mongodb
 
 import (
+	"context"
 	"encoding/gob"
+	"time"
 
 	"github.com/syb-devs/goth/database"
 	"gopkg.in/mgo.v2"
@@ -14,11 +16,20 @@ func init() {
 
 // Compile-time interface check
 var _ database.Connection = (*Conn)(nil)
+var _ database.Pinger = (*Conn)(nil)
 var _ database.Resource = (*Resource)(nil)
 
 // IsNotFound checks if the given error is a MongoDB not found errorr
 func IsNotFound(err error) bool {
-	return err == mgo.ErrNotFound
+	return err == mgo.ErrNotFound || err == database.ErrNotFound
+}
+
+// dbError translates MongoDB driver errors into the generic database errors
+func dbError(err error) error {
+	if err == mgo.ErrNotFound {
+		return database.ErrNotFound
+	}
+	return err
 }
 
 // Conn represents a MongoDB connection
@@ -63,6 +74,28 @@ func (c *Conn) Close() error {
 	return nil
 }
 
+// Ping checks that the MongoDB server is reachable, using a copy of the connection session
+func (c *Conn) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db, release := c.opSession(ctx)
+	return run(ctx, release, func() error {
+		return db.Session.Ping()
+	})
+}
+
+// opSession returns a copy of the connection session to run a single operation of a context,
+// which must be closed with the returned release function. If the context has a deadline, the
+// socket timeout of the copy expires with it.
+func (c *Conn) opSession(ctx context.Context) (*mgo.Database, func()) {
+	sess := c.Database.Session.Copy()
+	if deadline, ok := ctx.Deadline(); ok {
+		sess.SetSocketTimeout(time.Until(deadline))
+	}
+	return sess.DB(c.Database.Name), sess.Close
+}
+
 // Map returs the resource map associated with the connection
 func (c *Conn) Map() *database.ResourceMap {
 	return c.resMap
@@ -1,7 +1,10 @@
 package mongodb
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/syb-devs/goth/database"
 
@@ -25,8 +28,14 @@ func NewRepository(conn *Conn) *Repository {
 	}
 }
 
+// RepositoryFactory returns a Repository for the given MongoDB connection.
+// It can be used as the App database.RepositoryFactory.
+func RepositoryFactory(conn database.Connection) database.Repository {
+	return NewRepository(conn.(*Conn))
+}
+
 // Insert inserts the resource in the corresponding collection
-func (r *Repository) Insert(d database.Resource) error {
+func (r *Repository) Insert(ctx context.Context, d database.Resource) error {
 	colName, err := r.Conn.Map().ColFor(d)
 	if err != nil {
 		return err
@@ -35,17 +44,29 @@ func (r *Repository) Insert(d database.Resource) error {
 	if r.IDGenerator != nil && d.GetID().(bson.ObjectId).Hex() == "" {
 		d.SetID(r.IDGenerator())
 	}
-	return r.Conn.C(colName).Insert(d)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db, release := r.Conn.opSession(ctx)
+	return run(ctx, release, func() error {
+		return db.C(colName).Insert(d)
+	})
 }
 
 // Update updates the resource in the database
-func (r *Repository) Update(d database.Resource) error {
+func (r *Repository) Update(ctx context.Context, d database.Resource) error {
 	colName, err := r.Conn.Map().ColFor(d)
 	if err != nil {
 		return err
 	}
 	r.touch(d)
-	return r.Conn.C(colName).UpdateId(d.GetID(), d)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db, release := r.Conn.opSession(ctx)
+	return run(ctx, release, func() error {
+		return dbError(db.C(colName).UpdateId(d.GetID(), d))
+	})
 }
 
 func (r *Repository) touch(d database.Resource) {
@@ -55,7 +76,7 @@ func (r *Repository) touch(d database.Resource) {
 }
 
 // Delete deletes the resource from the database
-func (r *Repository) Delete(d database.Resource) error {
+func (r *Repository) Delete(ctx context.Context, d database.Resource) error {
 	colName, err := r.Conn.Map().ColFor(d)
 	if err != nil {
 		return err
@@ -65,54 +86,86 @@ func (r *Repository) Delete(d database.Resource) error {
 	if err != nil {
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db, release := r.Conn.opSession(ctx)
 	if delColName == "" {
-		return r.Conn.C(colName).RemoveId(d.GetID())
+		return run(ctx, release, func() error {
+			return dbError(db.C(colName).RemoveId(d.GetID()))
+		})
 	}
 
 	if td, ok := d.(database.SoftDeletable); ok {
 		// Logic delete
 		td.MarkDeleted()
 	}
-	err = r.Conn.C(delColName).Insert(d)
-	if err != nil {
-		return err
-	}
-	return r.Conn.C(colName).RemoveId(d.GetID())
+	return run(ctx, release, func() error {
+		err := db.C(delColName).Insert(d)
+		if err != nil {
+			return err
+		}
+		return dbError(db.C(colName).RemoveId(d.GetID()))
+	})
 }
 
 // Get retrieves a resource from the database and stores in the given type
-func (r *Repository) Get(ID interface{}, dest database.Resource) error {
+func (r *Repository) Get(ctx context.Context, ID interface{}, dest database.Resource) error {
 	if idstr, ok := ID.(string); ok {
+		// A malformed ID can not match any resource
+		if !bson.IsObjectIdHex(idstr) {
+			return database.ErrNotFound
+		}
 		ID = bson.ObjectIdHex(idstr)
 	}
-	return r.FindOne(dest, database.NewQ(bson.M{"_id": ID}))
+	return r.FindOne(ctx, dest, database.NewQ(bson.M{"_id": ID}))
 }
 
 // FindOne runs the given query, retrieving a single resource and stores in the given type
-func (r *Repository) FindOne(dest database.Resource, query database.Query) error {
-	it, err := r.query(dest, query)
+func (r *Repository) FindOne(ctx context.Context, dest database.Resource, query database.Query) error {
+	it, release, err := r.query(ctx, dest, query)
+	if err != nil {
+		return err
+	}
+	// The document is decoded once the query ends, so an abandoned query never writes to dest
+	var doc bson.Raw
+	err = run(ctx, release, func() error {
+		return dbError(it.One(&doc))
+	})
 	if err != nil {
 		return err
 	}
-	return it.One(dest)
+	return doc.Unmarshal(dest)
 }
 
 // FindMany runs the given query, retrieving all matching resources and stores in the given type slice
-func (r *Repository) FindMany(dest database.ResourceList, query database.Query) error {
-	it, err := r.query(dest, query)
+func (r *Repository) FindMany(ctx context.Context, dest database.ResourceList, query database.Query) error {
+	it, release, err := r.query(ctx, dest, query)
 	if err != nil {
 		return err
 	}
-	return it.All(dest)
+	var docs []bson.Raw
+	err = run(ctx, release, func() error {
+		return it.All(&docs)
+	})
+	if err != nil {
+		return err
+	}
+	return unmarshalAll(docs, dest)
 }
 
-func (r *Repository) query(dest interface{}, query database.Query) (*mgo.Query, error) {
+// query builds the query on a session copy for the context, which must be closed with the
+// returned release function once the query ends
+func (r *Repository) query(ctx context.Context, dest interface{}, query database.Query) (*mgo.Query, func(), error) {
 	colName, err := r.Conn.Map().ColFor(dest)
-
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
-	it := r.Conn.C(colName).Find(query.Where)
+	db, release := r.Conn.opSession(ctx)
+	it := db.C(colName).Find(query.Where)
 	if len(query.Sort) > 0 {
 		it.Sort(query.Sort...)
 	}
@@ -124,11 +177,60 @@ func (r *Repository) query(dest interface{}, query database.Query) (*mgo.Query,
 	if query.Skip != 0 {
 		it.Skip(query.Skip)
 	}
-	return it, nil
+	// Let the server abort the query once the context deadline is exceeded. The time limit is
+	// set in milliseconds, where 0 means no limit.
+	if deadline, ok := ctx.Deadline(); ok {
+		maxTime := time.Until(deadline)
+		if maxTime < time.Millisecond {
+			maxTime = time.Millisecond
+		}
+		it.SetMaxTime(maxTime)
+	}
+	return it, release, nil
+}
+
+// unmarshalAll decodes the documents into the slice pointed by dest, replacing its elements
+func unmarshalAll(docs []bson.Raw, dest interface{}) error {
+	slice := reflect.ValueOf(dest).Elem()
+	elemType := slice.Type().Elem()
+	list := reflect.MakeSlice(slice.Type(), 0, len(docs))
+	for _, doc := range docs {
+		elem := reflect.New(elemType)
+		if err := doc.Unmarshal(elem.Interface()); err != nil {
+			return err
+		}
+		list = reflect.Append(list, elem.Elem())
+	}
+	slice.Set(list)
+	return nil
+}
+
+// run executes a database operation on its own session copy, closed with the release function
+// once the operation ends. If the context is done first, the session copy is closed right away,
+// interrupting the operation, and the context error is returned without waiting for it.
+func run(ctx context.Context, release func(), op func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			// The operations of a closed session panic
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("mongodb: operation interrupted: %v", r)
+			}
+		}()
+		done <- op()
+	}()
+	select {
+	case err := <-done:
+		release()
+		return err
+	case <-ctx.Done():
+		release()
+		return ctx.Err()
+	}
 }
 
 // FetchRelated fetchs resouces related to the given resource
-func (r *Repository) FetchRelated(source database.Resource, relations ...string) error {
+func (r *Repository) FetchRelated(ctx context.Context, source database.Resource, relations ...string) error {
 	for _, relation := range relations {
 		rel, err := r.Conn.Map().Relationship(source, relation)
 		if err != nil {
@@ -140,19 +242,19 @@ func (r *Repository) FetchRelated(source database.Resource, relations ...string)
 			if ID == nil {
 				continue
 			}
-			dest := getTargetField(rel, source)
-			err = r.Get(ID, dest.(database.Resource))
+			dest := database.RelationshipTarget(rel, source)
+			err = r.Get(ctx, ID, dest.(database.Resource))
 			if err != nil {
 				return err
 			}
 		case database.HasMany:
-			IDs := fieldByName(source, rel.FieldName).([]bson.ObjectId)
+			IDs := database.RelationshipRefs(rel, source).([]bson.ObjectId)
 			if len(IDs) == 0 {
 				continue
 			}
 			where := bson.M{"_id": bson.M{"$in": IDs}}
-			dest := getTargetField(rel, source)
-			err = r.FindMany(dest, database.NewQ(where))
+			dest := database.RelationshipTarget(rel, source)
+			err = r.FindMany(ctx, dest, database.NewQ(where))
 			if err != nil {
 				return err
 			}
@@ -162,7 +264,7 @@ func (r *Repository) FetchRelated(source database.Resource, relations ...string)
 }
 
 func idFromField(rel database.Relationship, res database.Resource) *bson.ObjectId {
-	rawID := fieldByName(res, rel.FieldName)
+	rawID := database.RelationshipRefs(rel, res)
 	switch rel.Kind {
 	case database.HasOne:
 		val := rawID.(bson.ObjectId)
@@ -173,36 +275,3 @@ func idFromField(rel database.Relationship, res database.Resource) *bson.ObjectI
 		return nil
 	}
 }
-
-// getTargetField returns the struct field for storing related resources
-func getTargetField(rel database.Relationship, res database.Resource) interface{} {
-	if targeter, ok := res.(database.RelationshipTargeter); ok {
-		// The Resource provides a GetRelTarget method that gives us the target field
-		return targeter.RelationshipTarget(rel.Name)
-	}
-
-	v := reflect.ValueOf(res)
-	for v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-	f := v.FieldByName(rel.TargetField)
-	if f.Kind() != reflect.Ptr {
-		return f.Addr().Interface()
-	}
-	if !f.IsNil() {
-		return f.Interface()
-	}
-
-	zval := reflect.New(f.Type().Elem())
-	f.Set(zval)
-	return zval.Interface()
-}
-
-func fieldByName(i interface{}, field string) interface{} {
-	t := reflect.ValueOf(i)
-	// If pointer or slice, get it's element
-	for t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-	return t.FieldByName(field).Interface()
-}
@@ -1,7 +1,10 @@
 package mongodb
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/syb-devs/goth/database"
 
@@ -24,7 +27,7 @@ func benchmarkGetTargetFieldInterface(b *testing.B, u database.Resource) {
 	}
 	for n := 0; n < b.N; n++ {
 		for _, rel := range rels {
-			getTargetField(rel, u)
+			database.RelationshipTarget(rel, u)
 		}
 	}
 }
@@ -76,3 +79,60 @@ func (u *User2) RelationshipTarget(rel string) interface{} {
 		return nil
 	}
 }
+
+func TestRunContext(t *testing.T) {
+	errOp := errors.New("op failed")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	long, cancelLong := context.WithTimeout(context.Background(), time.Second)
+	defer cancelLong()
+	// Canceled without a deadline, i.e. when the client disconnects
+	disconnected, disconnect := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, disconnect)
+
+	tests := []struct {
+		ctx context.Context
+		op  func(released chan struct{}) error
+		err error
+	}{
+		{context.Background(), func(chan struct{}) error { return errOp }, errOp},
+		{canceled, func(chan struct{}) error { return nil }, context.Canceled},
+		{short, func(chan struct{}) error { time.Sleep(time.Second); return nil }, context.DeadlineExceeded},
+		{long, func(chan struct{}) error { return nil }, nil},
+		{disconnected, func(released chan struct{}) error { <-released; return errOp }, context.Canceled},
+	}
+
+	for i, test := range tests {
+		op, released := test.op, make(chan struct{})
+		start := time.Now()
+		err := run(test.ctx, func() { close(released) }, func() error { return op(released) })
+		if err != test.err {
+			t.Errorf("test #%d: expecting error %v, got %v", i+1, test.err, err)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("test #%d: expecting the operation to be abandoned, took %v", i+1, elapsed)
+		}
+		select {
+		case <-released:
+		default:
+			t.Errorf("test #%d: expecting the session to be released", i+1)
+		}
+	}
+}
+
+func TestRunPanic(t *testing.T) {
+	err := run(context.Background(), func() {}, func() error { panic("Session already closed") })
+	if err == nil {
+		t.Error("expecting an error from an operation panicking")
+	}
+}
+
+func TestGetMalformedID(t *testing.T) {
+	r := NewRepository(nil)
+	if err := r.Get(context.Background(), "not-an-id", &User{}); err != database.ErrNotFound {
+		t.Errorf("expecting error %v, got %v", database.ErrNotFound, err)
+	}
+}
@@ -81,3 +81,37 @@ func relKindFromField(f reflect.StructField) int {
 		return HasOne
 	}
 }
+
+// RelationshipTarget returns a pointer to the field of the resource storing the related resources,
+// allocating it first if it is a nil pointer
+func RelationshipTarget(rel Relationship, res Resource) interface{} {
+	if targeter, ok := res.(RelationshipTargeter); ok {
+		// The Resource provides a RelationshipTarget method that gives us the target field
+		return targeter.RelationshipTarget(rel.Name)
+	}
+
+	v := reflect.ValueOf(res)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	f := v.FieldByName(rel.TargetField)
+	if f.Kind() != reflect.Ptr {
+		return f.Addr().Interface()
+	}
+	if !f.IsNil() {
+		return f.Interface()
+	}
+
+	zval := reflect.New(f.Type().Elem())
+	f.Set(zval)
+	return zval.Interface()
+}
+
+// RelationshipRefs returns the value of the field of the resource referencing the related resources
+func RelationshipRefs(rel Relationship, res Resource) interface{} {
+	v := reflect.ValueOf(res)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return v.FieldByName(rel.FieldName).Interface()
+}
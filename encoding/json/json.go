@@ -4,8 +4,17 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+
+	"github.com/syb-devs/goth/encoding"
 )
 
+// MediaType is the media type the JSON Codec is registered for
+const MediaType = "application/json"
+
+func init() {
+	encoding.Register(MediaType, Codec{})
+}
+
 // Codec encodes and decodes data from/to JSON text
 type Codec struct{}
 
@@ -13,7 +22,7 @@ type Codec struct{}
 func (c Codec) Encode(w io.Writer, res interface{}) error {
 	if rw, ok := w.(http.ResponseWriter); ok {
 		if h := rw.Header().Get("Content-Type"); h == "" {
-			rw.Header().Set("Content-Type", "application/json")
+			rw.Header().Set("Content-Type", MediaType)
 		}
 	}
 	return json.NewEncoder(w).Encode(res)
@@ -0,0 +1,146 @@
+package encoding
+
+import (
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	codecs     = make(map[string]Codec)
+	mediaTypes []string
+	codecsMu   sync.RWMutex
+)
+
+// Register registers a Codec for the given media type (i.e. application/json), so it is used
+// for the requests and responses of that type. It panics if the media type is already registered.
+func Register(mediaType string, c Codec) {
+	mediaType = strings.ToLower(mediaType)
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if _, exists := codecs[mediaType]; exists {
+		panic(fmt.Sprintf("codec for media type %s already registered", mediaType))
+	}
+	codecs[mediaType] = c
+	mediaTypes = append(mediaTypes, mediaType)
+}
+
+// Lookup returns the Codec registered for the media type of a Content-Type header value,
+// ignoring its parameters (i.e. charset)
+func Lookup(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[mediaType]
+	return c, ok
+}
+
+// MediaTypes returns the registered media types, in registration order
+func MediaTypes() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	return append([]string(nil), mediaTypes...)
+}
+
+// Negotiate returns the registered media type, and its Codec, best matching an Accept header value.
+// Every registered media type gets the quality (q-value) of the most specific media range matching it,
+// and the highest one wins. Ties go to the preferred media type, then to the first registered one.
+// An empty Accept header accepts any media type. It returns false if no registered type is acceptable.
+func Negotiate(accept, preferred string) (string, Codec, bool) {
+	ranges := parseAccept(accept)
+	preferred = strings.ToLower(preferred)
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	best, bestQ := "", 0.0
+	for _, mediaType := range mediaTypes {
+		q := quality(ranges, mediaType)
+		if q > bestQ || (q == bestQ && q > 0 && mediaType == preferred) {
+			best, bestQ = mediaType, q
+		}
+	}
+	if best == "" {
+		return "", nil, false
+	}
+	return best, codecs[best], true
+}
+
+// mediaRange is a media range of an Accept header, i.e. text/*;q=0.5
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// specificity ranks the media range: a full media type is more specific than type/*,
+// which is more specific than */*
+func (r mediaRange) specificity() int {
+	switch {
+	case r.typ == "*":
+		return 0
+	case r.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+func (r mediaRange) matches(typ, subtype string) bool {
+	return (r.typ == "*" || r.typ == typ) && (r.subtype == "*" || r.subtype == subtype)
+}
+
+// parseAccept parses an Accept header value, skipping malformed media ranges.
+// An empty value is equivalent to */*.
+func parseAccept(accept string) []mediaRange {
+	if strings.TrimSpace(accept) == "" {
+		return []mediaRange{{typ: "*", subtype: "*", q: 1}}
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := cut(mediaType, "/")
+		if !ok || (typ == "*" && subtype != "*") {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		if qv, ok := params["q"]; ok {
+			q, err := strconv.ParseFloat(qv, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+			r.q = q
+		}
+		ranges = append(ranges, r)
+	}
+	// The most specific ranges go first, so they take precedence
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// quality returns the q-value of the most specific media range matching the media type,
+// or zero if none matches
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := cut(mediaType, "/")
+	for _, r := range ranges {
+		if r.matches(typ, subtype) {
+			return r.q
+		}
+	}
+	return 0
+}
+
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,81 @@
+package encoding_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/syb-devs/goth/encoding"
+	"github.com/syb-devs/goth/encoding/json"
+)
+
+type textCodec struct{}
+
+func (textCodec) Encode(io.Writer, interface{}) error { return nil }
+func (textCodec) Decode(io.Reader, interface{}) error { return nil }
+
+func init() {
+	encoding.Register("text/plain", textCodec{})
+	encoding.Register("Application/XML", textCodec{})
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		contentType string
+		found       bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"APPLICATION/JSON", true},
+		{"application/xml", true},
+		{"application/yaml", false},
+		{"", false},
+		{"not a media type", false},
+	}
+
+	for i, test := range tests {
+		if _, found := encoding.Lookup(test.contentType); found != test.found {
+			t.Errorf("test #%d: expecting found %v for %q, got %v", i+1, test.found, test.contentType, found)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept    string
+		preferred string
+		mediaType string
+	}{
+		{"", json.MediaType, json.MediaType},
+		{"", "text/plain", "text/plain"},
+		{"*/*", json.MediaType, json.MediaType},
+		{"text/plain", json.MediaType, "text/plain"},
+		{"text/*", json.MediaType, "text/plain"},
+		{"application/*", "", json.MediaType},
+		{"application/*", "application/xml", "application/xml"},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", json.MediaType, "application/xml"},
+		{"application/json;q=0.5, text/plain;q=0.7", json.MediaType, "text/plain"},
+		{"application/json;q=0.5, text/plain;q=0.5", json.MediaType, json.MediaType},
+		{"*/*, application/json;q=0", json.MediaType, "text/plain"},
+		{"application/*;q=0.2, application/xml", json.MediaType, "application/xml"},
+		{"text/html", json.MediaType, ""},
+		{"application/json;q=0", json.MediaType, ""},
+		{"application/json;q=2, text/plain", json.MediaType, "text/plain"},
+		{"garbage, application/json", json.MediaType, json.MediaType},
+	}
+
+	for i, test := range tests {
+		mediaType, codec, ok := encoding.Negotiate(test.accept, test.preferred)
+		if mediaType != test.mediaType || ok != (test.mediaType != "") || ok != (codec != nil) {
+			t.Errorf("test #%d: expecting media type %q for %q, got %q (%v)", i+1, test.mediaType, test.accept, mediaType, ok)
+		}
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expecting a panic registering a media type twice")
+		}
+	}()
+	encoding.Register("TEXT/PLAIN", textCodec{})
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/syb-devs/goth/app"
 	"github.com/syb-devs/goth/database"
 	"github.com/syb-devs/goth/log"
@@ -8,9 +10,9 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
-func createData(a *app.App) error {
+func createData(ctx context.Context, a *app.App) error {
 	users := []*User{}
-	err := a.DB.FindMany(&users, database.NewQ(nil))
+	err := a.DB.FindMany(ctx, &users, database.NewQ(nil))
 	if err != nil {
 		return err
 	}
@@ -32,7 +34,7 @@ func createData(a *app.App) error {
 	u.Name = "John"
 
 	for _, todo := range todos {
-		err = a.DB.Insert(&todo)
+		err = a.DB.Insert(ctx, &todo)
 		if err != nil {
 			return err
 		}
@@ -45,14 +47,14 @@ func createData(a *app.App) error {
 		Facebook: "https://www.facebook.com/johndoe",
 		Linkedin: "https://www.linkedin.com/in/john-doe",
 	}
-	err = a.DB.Insert(p)
+	err = a.DB.Insert(ctx, p)
 	if err != nil {
 		return err
 	}
 	log.Debugf("created profile: %+v", p)
 	u.ProfileID = &p.ID
 
-	err = a.DB.Insert(u)
+	err = a.DB.Insert(ctx, u)
 	if err != nil {
 		return err
 	}
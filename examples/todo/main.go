@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -10,11 +11,14 @@ import (
 	"github.com/syb-devs/goth/app/middleware/recovr"
 	"github.com/syb-devs/goth/app/middleware/timer"
 	"github.com/syb-devs/goth/app/mux/httptreemux"
+	"github.com/syb-devs/goth/auth/jwt"
 	"github.com/syb-devs/goth/database"
 	"github.com/syb-devs/goth/database/driver/mongodb"
+	"github.com/syb-devs/goth/health"
 	"github.com/syb-devs/goth/log"
 	"github.com/syb-devs/goth/rest"
 	"github.com/syb-devs/goth/user"
+	_ "github.com/syb-devs/goth/validate/required"
 
 	"github.com/syb-devs/dockerlink"
 
@@ -32,23 +36,37 @@ func main() {
 		Debug:          false,
 		AllowedHeaders: []string{"*"},
 	}
-	handler := cors.New(corsOpts).Handler(mux)
-	myApp.SetHandler(handler)
 
 	mainChain := chain.New(
+		app.HTTPMiddleware(cors.New(corsOpts).Handler),
 		buffer.New(),
 		recovr.New(),
 		timer.New(),
-		errMiddleware,
 	)
 	myApp.AddChain(mainChain, "main")
 	myApp.AddChain(mainChain, "pub")
 
 	myApp.Handle("GET", "/", myApp.WrapHandlerFunc(rootHandler, "main"))
 
+	// Configuration: defaults, optional file from CONFIG_FILE and environment variables
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := myApp.Config.LoadFile(path); err != nil {
+			panic(err)
+		}
+	}
+	mongoCfg := mongoConfig{Database: "goth"}
+	if err := myApp.Config.Section("mongodb", &mongoCfg); err != nil {
+		panic(err)
+	}
+	jwtCfg := jwtConfig{}
+	if err := myApp.Config.Section("jwt", &jwtCfg); err != nil {
+		panic(err)
+	}
+	jwt.Secret = []byte(jwtCfg.Secret)
+
 	ps := database.ConnectionParams{
-		"url":      getMongoURI(),
-		"database": "goth",
+		"url":      getMongoURI(mongoCfg),
+		"database": mongoCfg.Database,
 	}
 	conn, err := mongodb.NewConnection(ps, database.NewResourceMap())
 	if err != nil {
@@ -57,42 +75,46 @@ func main() {
 	myApp.DB.Connection = conn
 	myApp.DB.ResourceMap = conn.Map()
 	myApp.DB.Repository = mongodb.NewRepository(conn)
+	myApp.DB.NewRepository = mongodb.RepositoryFactory
 
 	myApp.DB.RegisterResource(Todo{}, "todos", "")
 	myApp.DB.RegisterResource(User{}, "users", "")
 	myApp.DB.RegisterResource(Profile{}, "profiles", "")
 
 	user.RegisterType(&User{}, "username")
+	myApp.Use(user.NewModule(), health.NewModule(health.Options{}))
 
 	rest.Register(myApp, &Todo{}, "todos")
 	rest.Register(myApp, &User{}, "users")
 	rest.Register(myApp, &Profile{}, "profiles")
 
-	createData(myApp)
-	myApp.Run()
+	// Route listing for development
+	if os.Getenv("DEBUG_ROUTES") != "" {
+		myApp.Group("", "main").HandleFunc("GET", "/_routes", myApp.RoutesHandler)
+	}
+
+	createData(context.Background(), myApp)
+	if err := myApp.Run(); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+}
+
+type mongoConfig struct {
+	URL      string `json:"url" env:"MONGO_URL"`
+	Database string `json:"database" validate:"required"`
+}
+
+type jwtConfig struct {
+	Secret string `json:"secret" env:"JWT_SECRET"`
 }
 
-func getMongoURI() string {
-	if uri := os.Getenv("MONGO_URL"); uri != "" {
-		return uri
+func getMongoURI(cfg mongoConfig) string {
+	if cfg.URL != "" {
+		return cfg.URL
 	}
 	if link, err := dockerlink.GetLink("mongodb", 27017, "tcp"); err == nil {
 		return fmt.Sprintf("%s:%d", link.Address, link.Port)
 	}
-	panic("mongodb connection not found, use MONGO_URL env var or a docker link with mongodb name")
-}
-
-func errMiddleware(h app.Handler) app.Handler {
-	f := func(ctx *app.Context) error {
-		err := h.Serve(ctx)
-		if err != nil {
-			log.Errorf(
-				"error serving %s %s: %v",
-				ctx.Request.Method,
-				ctx.Request.URL.String(),
-				err)
-		}
-		return nil
-	}
-	return app.HandlerFunc(f)
+	panic("mongodb connection not found, use MONGO_URL env var, the mongodb.url setting or a docker link with mongodb name")
 }
@@ -0,0 +1,232 @@
+// Package health implements an App module exposing liveness and readiness endpoints,
+// which report the aggregated status of a set of pluggable checks.
+//
+// Checks are contributed by the modules implementing the Checker interface, by the App database
+// connection if it implements database.Pinger, and by calling Module.AddCheck.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/database"
+)
+
+// ModuleName is the name of the health module
+const ModuleName = "goth.health"
+
+const (
+	// StatusOK is the status of a passing check, or of a report whose checks all passed
+	StatusOK = "ok"
+	// StatusFail is the status of a failing check, or of a report with any failing check
+	StatusFail = "fail"
+
+	// DefaultLivenessPath is the default path of the liveness endpoint
+	DefaultLivenessPath = "/healthz"
+	// DefaultReadinessPath is the default path of the readiness endpoint
+	DefaultReadinessPath = "/readyz"
+	// DefaultTimeout is the default time a check is given to complete
+	DefaultTimeout = 5 * time.Second
+
+	// DatabaseCheck is the name of the check contributed by the App database connection
+	DatabaseCheck = "database"
+)
+
+// CheckFunc checks the health of a component, returning an error if it is not healthy.
+// It should return as soon as the given context is done.
+type CheckFunc func(ctx context.Context) error
+
+// Check is a named health check
+type Check struct {
+	Name string
+	Func CheckFunc
+	// Timeout is the time the check is given to complete. Defaults to the module timeout.
+	Timeout time.Duration
+	// Liveness checks are run by both endpoints; the rest only by the readiness one
+	Liveness bool
+}
+
+// Checker is implemented by the modules contributing health checks
+type Checker interface {
+	HealthChecks() []Check
+}
+
+// Result is the outcome of running a check
+type Result struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report is the aggregated outcome of running a set of checks
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Options are used to setup the health module
+type Options struct {
+	// LivenessPath is the path of the liveness endpoint. Defaults to DefaultLivenessPath.
+	LivenessPath string
+	// ReadinessPath is the path of the readiness endpoint. Defaults to DefaultReadinessPath.
+	ReadinessPath string
+	// Timeout is the default timeout of the checks. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// Group is the route group the endpoints are registered in. If nil, they are registered
+	// at the App root, without any middleware chain.
+	Group *app.Group
+}
+
+// Module is the health module
+type Module struct {
+	*app.BaseModule
+	opts Options
+
+	mu     sync.Mutex
+	checks []Check
+}
+
+// NewModule returns the health module, which registers the liveness and readiness endpoints.
+// Add it to an App with App.Use.
+func NewModule(opts Options) *Module {
+	if opts.LivenessPath == "" {
+		opts.LivenessPath = DefaultLivenessPath
+	}
+	if opts.ReadinessPath == "" {
+		opts.ReadinessPath = DefaultReadinessPath
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	return &Module{
+		BaseModule: app.NewBaseModule(ModuleName),
+		opts:       opts,
+	}
+}
+
+// AddCheck adds checks to the module. It panics if a check name is already in use.
+func (m *Module) AddCheck(checks ...Check) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range checks {
+		for _, existing := range m.checks {
+			if existing.Name == c.Name {
+				panic(fmt.Sprintf("health check %s already registered", c.Name))
+			}
+		}
+		m.checks = append(m.checks, c)
+	}
+}
+
+// Bootstrap registers the liveness and readiness endpoints
+func (m *Module) Bootstrap(a *app.App) error {
+	live := app.HandlerFunc(func(ctx *app.Context) error {
+		return m.serve(ctx, true)
+	})
+	ready := app.HandlerFunc(func(ctx *app.Context) error {
+		return m.serve(ctx, false)
+	})
+
+	handle := a.Handle
+	if m.opts.Group != nil {
+		handle = m.opts.Group.Handle
+	}
+	handle("GET", m.opts.LivenessPath, live).Named("health.liveness")
+	handle("GET", m.opts.ReadinessPath, ready).Named("health.readiness")
+	return nil
+}
+
+// Run runs the checks of the App, only the liveness ones if requested, and returns the report
+func (m *Module) Run(ctx context.Context, a *app.App, liveness bool) *Report {
+	var checks []Check
+	for _, c := range m.Checks(a) {
+		if c.Liveness || !liveness {
+			checks = append(checks, c)
+		}
+	}
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			results[i] = m.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Checks[c.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// Checks returns the checks of the App, sorted by name: those added to the module,
+// the ones contributed by the modules implementing Checker and the database one.
+func (m *Module) Checks(a *app.App) []Check {
+	m.mu.Lock()
+	checks := append([]Check(nil), m.checks...)
+	m.mu.Unlock()
+
+	for _, mod := range a.Modules() {
+		if checker, ok := mod.(Checker); ok {
+			checks = append(checks, checker.HealthChecks()...)
+		}
+	}
+	if pinger, ok := a.DB.Connection.(database.Pinger); ok {
+		checks = append(checks, Check{Name: DatabaseCheck, Func: pinger.Ping})
+	}
+	sort.SliceStable(checks, func(i, j int) bool {
+		return checks[i].Name < checks[j].Name
+	})
+	return checks
+}
+
+// run runs a check with its timeout, measuring its latency
+func (m *Module) run(ctx context.Context, c Check) Result {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = m.opts.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.Func(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Status: StatusOK, Latency: time.Since(start).String()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// serve runs the checks and encodes the report, with a 503 status if any check failed
+func (m *Module) serve(ctx *app.Context, liveness bool) error {
+	report := m.Run(ctx.Context(), ctx.App, liveness)
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.Header().Set("Cache-Control", "no-store")
+	return ctx.EncodeStatus(status, report)
+}
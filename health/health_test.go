@@ -0,0 +1,114 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+	"github.com/syb-devs/goth/database"
+	"github.com/syb-devs/goth/health"
+	"github.com/syb-devs/goth/log"
+)
+
+type pingConn struct {
+	database.Connection
+	err error
+}
+
+func (c *pingConn) Copy() database.Connection  { return c }
+func (c *pingConn) Close() error               { return nil }
+func (c *pingConn) Ping(context.Context) error { return c.err }
+
+type cacheModule struct {
+	*app.BaseModule
+}
+
+func (m *cacheModule) Bootstrap(*app.App) error { return nil }
+
+func (m *cacheModule) HealthChecks() []health.Check {
+	return []health.Check{{Name: "cache", Func: func(context.Context) error { return nil }, Liveness: true}}
+}
+
+func newApp(t *testing.T, dbErr error, checks ...health.Check) (*app.App, *health.Module) {
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	a.SetMuxer(stdmux.New(a.NewContextHTTP))
+	a.DB.Connection = &pingConn{err: dbErr}
+	m := health.NewModule(health.Options{Timeout: 50 * time.Millisecond})
+	m.AddCheck(checks...)
+	a.Use(m, &cacheModule{BaseModule: app.NewBaseModule("cache")})
+	if err := m.Bootstrap(a); err != nil {
+		t.Fatal(err)
+	}
+	return a, m
+}
+
+func get(t *testing.T, a *app.App, path string) (int, health.Report) {
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	var report health.Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("error decoding report: %v", err)
+	}
+	return rec.Code, report
+}
+
+func TestEndpoints(t *testing.T) {
+	slow := health.Check{Name: "slow", Func: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	tests := []struct {
+		dbErr    error
+		checks   []health.Check
+		path     string
+		status   int
+		expected map[string]string
+	}{
+		{nil, nil, "/readyz", 200, map[string]string{"cache": "ok", "database": "ok"}},
+		{errors.New("no reachable servers"), nil, "/readyz", 503, map[string]string{"cache": "ok", "database": "fail"}},
+		{errors.New("no reachable servers"), nil, "/healthz", 200, map[string]string{"cache": "ok"}},
+		{nil, []health.Check{slow}, "/readyz", 503, map[string]string{"cache": "ok", "database": "ok", "slow": "fail"}},
+	}
+
+	for i, test := range tests {
+		a, _ := newApp(t, test.dbErr, test.checks...)
+		status, report := get(t, a, test.path)
+		if status != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, status)
+		}
+		if len(report.Checks) != len(test.expected) {
+			t.Errorf("test #%d: expecting checks %v, got %v", i+1, test.expected, report.Checks)
+		}
+		for name, expected := range test.expected {
+			res := report.Checks[name]
+			if res.Status != expected {
+				t.Errorf("test #%d: expecting check %s status %s, got %+v", i+1, name, expected, res)
+			}
+			if res.Latency == "" {
+				t.Errorf("test #%d: expecting check %s latency", i+1, name)
+			}
+			if expected == health.StatusFail && res.Error == "" {
+				t.Errorf("test #%d: expecting check %s error", i+1, name)
+			}
+		}
+	}
+}
+
+func TestDuplicateCheck(t *testing.T) {
+	m := health.NewModule(health.Options{})
+	noop := func(context.Context) error { return nil }
+	m.AddCheck(health.Check{Name: "a", Func: noop})
+	defer func() {
+		if recover() == nil {
+			t.Error("expecting a panic when adding a check twice")
+		}
+	}()
+	m.AddCheck(health.Check{Name: "a", Func: noop})
+}
@@ -1,11 +1,11 @@
 package rest
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/syb-devs/goth/app"
 	"github.com/syb-devs/goth/database"
@@ -13,10 +13,12 @@ import (
 
 const (
 	resourceIDParam = "resource_id"
+	// DefaultIDType is the URL parameter constraint used by default for the resource IDs
+	DefaultIDType = "objectid"
 )
 
-// ErrNotAllowed
-var ErrNotAllowed = errors.New("operation not allowed")
+// ErrNotAllowed is returned when the user is not allowed to operate on a resource
+var ErrNotAllowed = app.ErrAccessDenied
 
 // CRUDHandler interface represents the HTTP interface for CRUD operations
 // than can be applied to a Resource
@@ -38,21 +40,46 @@ func RegisterWithOptions(a *app.App, res database.Resource, opts Options, name s
 	RegisterCRUD(a, NewWithOptions(res, opts), name)
 }
 
-// RegisterCRUD registers a CRUDHandler
+// RegisterCRUD registers a CRUDHandler at the root path, using the main chain
 func RegisterCRUD(a *app.App, crud CRUDHandler, name string) {
+	Mount(a.Group("", "main"), crud, name)
+}
+
+// Mount registers the routes of a CRUDHandler in the given route group.
+// Routes are named after the group prefix, the resource name and the action, so the same
+// resource can be mounted in several groups (i.e. todos.retrieve, or api.v1.todos.retrieve
+// for the group /api/v1).
+func Mount(g *app.Group, crud CRUDHandler, name string) {
 	URL := fmt.Sprintf("/%s", name)
 	URLWithID := fmt.Sprintf("/%s/:%s", name, resourceIDParam)
 
+	if base, ok := crud.(*BaseCRUD); ok && base.idType != "" {
+		URLWithID += "<" + base.idType + ">"
+	}
+	routeName := strings.Trim(strings.ReplaceAll(g.Prefix(), "/", "."), ".")
+	if routeName != "" {
+		routeName += "."
+	}
+	routeName += name
+
 	// Register CRUD routes for Resource
-	a.Handle("POST", URL, a.WrapHandlerFunc(crud.Create, "main"))
-	a.Handle("GET", URLWithID, a.WrapHandlerFunc(crud.Retrieve, "main"))
-	a.Handle("PUT", URLWithID, a.WrapHandlerFunc(crud.Update, "main"))
-	a.Handle("DELETE", URLWithID, a.WrapHandlerFunc(crud.Delete, "main"))
-	a.Handle("GET", URL, a.WrapHandlerFunc(crud.List, "main"))
+	retrieve := g.HandleFunc("GET", URLWithID, crud.Retrieve).Named(routeName + ".retrieve")
+	create := crud.Create
+	if base, ok := crud.(*BaseCRUD); ok {
+		// The created resources are located with the retrieve route of this mount
+		create = func(ctx *app.Context) error {
+			return base.create(ctx, retrieve)
+		}
+	}
+	g.HandleFunc("POST", URL, create).Named(routeName + ".create")
+	g.HandleFunc("PUT", URLWithID, crud.Update).Named(routeName + ".update")
+	g.HandleFunc("DELETE", URLWithID, crud.Delete).Named(routeName + ".delete")
+	g.HandleFunc("GET", URL, crud.List).Named(routeName + ".list")
 }
 
 // BaseCRUD is the default implementation for ResourceHandler interface
 type BaseCRUD struct {
+	idType        string
 	resourceLimit int
 	resourceType  reflect.Type
 	ownerField    string
@@ -65,11 +92,15 @@ type Options struct {
 	ResourceLimit int
 	OwnerDBField  string
 	AccessChecker app.ResourceAccessChecker
+	// IDType is the constraint for the resource ID URL parameter (see app.RegisterParamType).
+	// Defaults to DefaultIDType; use "-" for no constraint.
+	IDType string
 }
 
 // New allocates and returns a BaseCRUD
 func New(resource database.Resource) *BaseCRUD {
 	return &BaseCRUD{
+		idType:        DefaultIDType,
 		resourceLimit: 100,
 		resourceType:  reflect.TypeOf(resource),
 		ownerField:    "",
@@ -90,6 +121,13 @@ func NewWithOptions(resource database.Resource, opts Options) *BaseCRUD {
 	if opts.OwnerDBField != "" {
 		crud.ownerField = opts.OwnerDBField
 	}
+	switch opts.IDType {
+	case "":
+	case "-":
+		crud.idType = ""
+	default:
+		crud.idType = opts.IDType
+	}
 	return crud
 }
 
@@ -105,6 +143,15 @@ func (h *BaseCRUD) NewResourceList(ctx *app.Context) database.ResourceList {
 
 // Create decodes a resource from the Request, validates it and stores it in the database
 func (h *BaseCRUD) Create(ctx *app.Context) error {
+	return h.create(ctx, nil)
+}
+
+// create stores a new resource, setting the Location header with the given retrieve route, if any
+func (h *BaseCRUD) create(ctx *app.Context, retrieve *app.Route) error {
+	// Fail before storing anything if the response can not be encoded
+	if _, _, err := ctx.Encoder(); err != nil {
+		return err
+	}
 	res := h.NewResource(ctx)
 	err := ctx.Decode(res)
 	if err != nil {
@@ -115,18 +162,21 @@ func (h *BaseCRUD) Create(ctx *app.Context) error {
 		return err
 	}
 	res.SetOwnerID(ctx.User.GetID())
-	err = ctx.App.DB.Insert(res)
+	err = ctx.DB.Insert(ctx.Context(), res)
 	if err != nil {
 		return err
 	}
-	return ctx.Encode(res)
+	if retrieve != nil {
+		h.setLocation(ctx, retrieve, res)
+	}
+	return ctx.EncodeStatus(http.StatusCreated, res)
 }
 
 // Retrieve fetches a resource from the database and encodes it to the ResponseWriter
 func (h *BaseCRUD) Retrieve(ctx *app.Context) error {
 	ID := ctx.URLParams.ByName(resourceIDParam)
 	res := h.NewResource(ctx)
-	err := ctx.App.DB.Get(ID, res)
+	err := ctx.DB.Get(ctx.Context(), ID, res)
 	if err != nil {
 		return err
 	}
@@ -143,9 +193,13 @@ func (h *BaseCRUD) Retrieve(ctx *app.Context) error {
 
 // Update decodes a resource from the Request, validates it and updates it in the database
 func (h *BaseCRUD) Update(ctx *app.Context) error {
+	// Fail before updating anything if the response can not be encoded
+	if _, _, err := ctx.Encoder(); err != nil {
+		return err
+	}
 	res := h.NewResource(ctx)
 	ID := ctx.URLParams.ByName(resourceIDParam)
-	err := ctx.App.DB.Get(ID, res)
+	err := ctx.DB.Get(ctx.Context(), ID, res)
 	if err != nil {
 		return err
 	}
@@ -161,7 +215,7 @@ func (h *BaseCRUD) Update(ctx *app.Context) error {
 	if err != nil {
 		return err
 	}
-	err = ctx.App.DB.Update(res)
+	err = ctx.DB.Update(ctx.Context(), res)
 	if err != nil {
 		return err
 	}
@@ -172,7 +226,7 @@ func (h *BaseCRUD) Update(ctx *app.Context) error {
 func (h *BaseCRUD) Delete(ctx *app.Context) error {
 	res := h.NewResource(ctx)
 	ID := ctx.URLParams.ByName(resourceIDParam)
-	err := ctx.App.DB.Get(ID, res)
+	err := ctx.DB.Get(ctx.Context(), ID, res)
 	if err != nil {
 		return err
 	}
@@ -180,7 +234,7 @@ func (h *BaseCRUD) Delete(ctx *app.Context) error {
 	if err != nil {
 		return err
 	}
-	return ctx.App.DB.Delete(res)
+	return ctx.DB.Delete(ctx.Context(), res)
 }
 
 // List retrieves a list of Resources from the database, and encodes it to the ResponseWriter
@@ -190,7 +244,7 @@ func (h *BaseCRUD) List(ctx *app.Context) error {
 	if err != nil {
 		return err
 	}
-	err = ctx.App.DB.FindMany(list, query)
+	err = ctx.DB.FindMany(ctx.Context(), list, query)
 	if err != nil {
 		return err
 	}
@@ -201,6 +255,21 @@ func (h *BaseCRUD) List(ctx *app.Context) error {
 	return ctx.Encode(list)
 }
 
+// setLocation sets the Location header with the URL of the given resource
+func (h *BaseCRUD) setLocation(ctx *app.Context, retrieve *app.Route, res database.Resource) {
+	idRes, ok := res.(interface {
+		GetIDString() string
+	})
+	if !ok {
+		return
+	}
+	URL, err := retrieve.URL(app.URLParams{resourceIDParam: idRes.GetIDString()})
+	if err != nil {
+		return
+	}
+	ctx.Header().Set("Location", URL)
+}
+
 func (h *BaseCRUD) queryFromURL(ctx *app.Context) (database.Query, error) {
 	getInt := func(field string) (int, error) {
 		val := ctx.Request.URL.Query().Get(field)
@@ -239,7 +308,6 @@ func (h *BaseCRUD) checkAccess(ctx *app.Context, res database.Resource, action s
 	if allowed {
 		return nil
 	}
-	ctx.WriteHeader(http.StatusForbidden)
 	return ErrNotAllowed
 }
 
@@ -248,7 +316,7 @@ func expandResource(ctx *app.Context, res database.Resource) error {
 	if len(rels) == 0 {
 		return nil
 	}
-	return ctx.App.DB.FetchRelated(res, rels...)
+	return ctx.DB.FetchRelated(ctx.Context(), res, rels...)
 }
 
 func expandResourceList(ctx *app.Context, list interface{}) error {
@@ -0,0 +1,305 @@
+// Package static implements an App module serving the files of a directory or fs.FS at a path
+// prefix, with conditional requests (ETag and Last-Modified), precompressed variants of the files,
+// Cache-Control rules by extension, and an optional fallback to index.html for single-page apps.
+//
+// The files are served through the route group of the module, so they go through its middleware
+// chain as any other handler of the App:
+//
+//	a.Use(static.NewModule(static.Options{
+//		Dir:          "./web/dist",
+//		SPA:          true,
+//		CacheControl: map[string]string{".html": "no-cache", "": "public, max-age=31536000, immutable"},
+//		Group:        a.Group("", "public"),
+//	}))
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/syb-devs/goth/app"
+)
+
+// ModuleName is the default name of the static module
+const ModuleName = "goth.static"
+
+// DefaultIndex is the default name of the file served for the directories
+const DefaultIndex = "index.html"
+
+// ErrNoFiles is returned by Bootstrap when the module has neither a directory nor an fs.FS to serve
+var ErrNoFiles = errors.New("static: no directory or fs.FS to serve")
+
+// encodings are the precompressed variants looked up for the files, by order of preference
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Options are used to setup the static module
+type Options struct {
+	// Name is the name of the module, to add several of them to an App. Defaults to ModuleName.
+	Name string
+	// Prefix is the path the files are served at, relative to the Group. Defaults to "/".
+	Prefix string
+	// Dir is the directory of the files. It is ignored if FS is set.
+	Dir string
+	// FS is the file system of the files, i.e. an embed.FS
+	FS fs.FS
+	// Index is the file served for the directories. Defaults to DefaultIndex.
+	Index string
+	// SPA serves the Index file of the root for the paths which do not match any file, so they
+	// are routed by the single-page app in the browser. The paths whose last segment has an
+	// extension are still answered with 404 Not Found, as they are missing assets.
+	SPA bool
+	// CacheControl maps the file extensions (i.e. ".js") to the Cache-Control header sent with
+	// them. The value for the "" key is sent with the rest of the files.
+	CacheControl map[string]string
+	// Group is the route group the files are served in. If nil, they are served at the App root,
+	// without any middleware chain.
+	Group *app.Group
+}
+
+// Module is the static module
+type Module struct {
+	*app.BaseModule
+	opts Options
+	fsys fs.FS
+
+	// etags caches the ETags computed from the content of the files without modification time
+	etags sync.Map
+}
+
+// NewModule returns the static module, which serves the files at the configured prefix.
+// Add it to an App with App.Use.
+func NewModule(opts Options) *Module {
+	if opts.Name == "" {
+		opts.Name = ModuleName
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "/"
+	}
+	if opts.Index == "" {
+		opts.Index = DefaultIndex
+	}
+	fsys := opts.FS
+	if fsys == nil && opts.Dir != "" {
+		fsys = os.DirFS(opts.Dir)
+	}
+	return &Module{
+		BaseModule: app.NewBaseModule(opts.Name),
+		opts:       opts,
+		fsys:       fsys,
+	}
+}
+
+// Bootstrap registers the GET and HEAD routes serving the files
+func (m *Module) Bootstrap(a *app.App) error {
+	if m.fsys == nil {
+		return ErrNoFiles
+	}
+	handle := a.Handle
+	if m.opts.Group != nil {
+		handle = m.opts.Group.Handle
+	}
+	prefix := strings.TrimSuffix(m.opts.Prefix, "/")
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		handle(method, prefix+"/*"+app.MountParam, m)
+	}
+	return nil
+}
+
+// Serve serves the file for the path below the prefix of the module
+func (m *Module) Serve(ctx *app.Context) error {
+	name := strings.TrimPrefix(path.Clean("/"+ctx.URLParams.ByName(app.MountParam)), "/")
+	if name == "" {
+		name = "."
+	}
+	if hidden(name) {
+		return app.ErrNotFound
+	}
+
+	f, fi, err := m.open(name)
+	if err == nil && fi.IsDir() {
+		f.Close()
+		if u := *ctx.Request.URL; !strings.HasSuffix(u.Path, "/") {
+			// Redirect to the directory URL, so the relative links of its index work
+			u.Path, u.RawPath = u.Path+"/", ""
+			http.Redirect(ctx.ResponseWriter, ctx.Request, u.RequestURI(), http.StatusMovedPermanently)
+			return nil
+		}
+		name = path.Join(name, m.opts.Index)
+		f, fi, err = m.open(name)
+	}
+	if errors.Is(err, fs.ErrNotExist) && m.opts.SPA && path.Ext(name) == "" {
+		name = m.opts.Index
+		f, fi, err = m.open(name)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+			return app.ErrNotFound
+		}
+		return err
+	}
+	defer f.Close()
+	if fi.IsDir() {
+		return app.ErrNotFound
+	}
+	return m.serveFile(ctx, name, f, fi)
+}
+
+// serveFile writes the file, or its best precompressed variant accepted by the client,
+// answering the conditional and range requests
+func (m *Module) serveFile(ctx *app.Context, name string, f fs.File, fi fs.FileInfo) error {
+	w, r := ctx.ResponseWriter, ctx.Request
+	h := w.Header()
+
+	content, err := readSeeker(f)
+	if err != nil {
+		return err
+	}
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		// Sniff the type from the original content, not the compressed one
+		var buf [512]byte
+		n, _ := io.ReadFull(content, buf[:])
+		ctype = http.DetectContentType(buf[:n])
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	h.Set("Content-Type", ctype)
+	if cc, ok := m.opts.CacheControl[path.Ext(name)]; ok {
+		h.Set("Cache-Control", cc)
+	} else if cc, ok := m.opts.CacheControl[""]; ok {
+		h.Set("Cache-Control", cc)
+	}
+
+	varied := false
+	for _, enc := range encodings {
+		vf, vfi, err := m.open(name + enc.ext)
+		if err != nil {
+			continue
+		}
+		if vfi.IsDir() {
+			vf.Close()
+			continue
+		}
+		varied = true
+		if !acceptsEncoding(r, enc.name) {
+			vf.Close()
+			continue
+		}
+		defer vf.Close()
+		if content, err = readSeeker(vf); err != nil {
+			return err
+		}
+		h.Set("Content-Encoding", enc.name)
+		name, fi = name+enc.ext, vfi
+		break
+	}
+	if varied {
+		h.Add("Vary", "Accept-Encoding")
+	}
+
+	etag, err := m.etag(name, fi, content)
+	if err != nil {
+		return err
+	}
+	h.Set("ETag", etag)
+	http.ServeContent(w, r, name, fi.ModTime(), content)
+	return nil
+}
+
+// open opens the named file of the module file system, returning its info
+func (m *Module) open(name string) (fs.File, fs.FileInfo, error) {
+	f, err := m.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
+
+// etag returns the ETag of the file, from its size and modification time, or from its content
+// if it has no modification time (i.e. embedded files)
+func (m *Module) etag(name string, fi fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !fi.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()), nil
+	}
+	if etag, ok := m.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	m.etags.Store(name, etag)
+	return etag, nil
+}
+
+// readSeeker returns the file as an io.ReadSeeker, reading it into memory if it is not one
+func readSeeker(f fs.File) (io.ReadSeeker, error) {
+	if rs, ok := f.(io.ReadSeeker); ok {
+		return rs, nil
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// hidden checks if any segment of the path is a dot file or directory
+func hidden(name string) bool {
+	for _, seg := range strings.Split(name, "/") {
+		if strings.HasPrefix(seg, ".") && seg != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding checks if the Accept-Encoding header of the request allows the given content coding
+func acceptsEncoding(r *http.Request, coding string) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			params := strings.Split(part, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), coding) {
+				continue
+			}
+			for _, p := range params[1:] {
+				p = strings.TrimSpace(p)
+				if strings.HasPrefix(p, "q=") {
+					if q, err := strconv.ParseFloat(p[2:], 64); err == nil && q == 0 {
+						return false
+					}
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package static_test
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+	"github.com/syb-devs/goth/log"
+	"github.com/syb-devs/goth/static"
+)
+
+var files = fstest.MapFS{
+	"index.html":         {Data: []byte("<html>app</html>")},
+	"app.js":             {Data: []byte("console.log('app')")},
+	"app.js.br":          {Data: []byte("brotli")},
+	"app.js.gz":          {Data: []byte("gzip")},
+	"docs/index.html":    {Data: []byte("<html>docs</html>")},
+	"docs/LICENSE":       {Data: []byte("plain text license")},
+	".env":               {Data: []byte("SECRET=1")},
+	"assets/.git/config": {Data: []byte("secret")},
+	"assets/logo.svg":    {Data: []byte("<svg></svg>")},
+	"assets/logo.svg.gz": {Mode: fs.ModeDir},
+}
+
+func newApp(t *testing.T, opts static.Options) *app.App {
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	a.SetMuxer(stdmux.New(a.NewContextHTTP))
+	a.AddChain(chain.New(func(h app.Handler) app.Handler {
+		return app.HandlerFunc(func(ctx *app.Context) error {
+			ctx.Header().Set("X-Chain", "public")
+			return h.Serve(ctx)
+		})
+	}), "public")
+	opts.Group = a.Group("", "public")
+	m := static.NewModule(opts)
+	a.Use(m)
+	if err := m.Bootstrap(a); err != nil {
+		t.Fatal(err)
+	}
+	a.Handle("GET", "/api/todos", app.HandlerFunc(func(ctx *app.Context) error {
+		return ctx.Encode([]string{})
+	}))
+	return a
+}
+
+func TestServe(t *testing.T) {
+	a := newApp(t, static.Options{
+		FS:           files,
+		SPA:          true,
+		CacheControl: map[string]string{".html": "no-cache", "": "max-age=3600"},
+	})
+
+	tests := []struct {
+		path     string
+		encoding string
+		status   int
+		body     string
+		header   map[string]string
+	}{
+		{"/", "", 200, "<html>app</html>", map[string]string{"Content-Type": "text/html; charset=utf-8", "Cache-Control": "no-cache", "X-Chain": "public"}},
+		{"/app.js", "", 200, "console.log('app')", map[string]string{"Cache-Control": "max-age=3600", "Vary": "Accept-Encoding", "Content-Encoding": ""}},
+		{"/app.js", "gzip, br", 200, "brotli", map[string]string{"Content-Encoding": "br", "Content-Type": "text/javascript; charset=utf-8"}},
+		{"/app.js", "gzip, br;q=0", 200, "gzip", map[string]string{"Content-Encoding": "gzip"}},
+		{"/assets/logo.svg", "gzip", 200, "<svg></svg>", map[string]string{"Content-Encoding": "", "Vary": ""}},
+		{"/docs", "", 301, "", map[string]string{"Location": "/docs/"}},
+		{"/docs/", "", 200, "<html>docs</html>", nil},
+		{"/docs/LICENSE", "", 200, "plain text license", map[string]string{"Content-Type": "text/plain; charset=utf-8"}},
+		{"/todos/42/edit", "", 200, "<html>app</html>", map[string]string{"Cache-Control": "no-cache"}},
+		{"/missing.js", "", 404, "", nil},
+		{"/.env", "", 404, "", nil},
+		{"/assets/.git/config", "", 404, "", nil},
+		{"/docs/../../static_test.go", "", 404, "", nil},
+		{"/api/todos", "", 200, "[]\n", map[string]string{"X-Chain": ""}},
+	}
+
+	for i, test := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", test.path, nil)
+		if test.encoding != "" {
+			req.Header.Set("Accept-Encoding", test.encoding)
+		}
+		a.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d for %s, got %d", i+1, test.status, test.path, rec.Code)
+			continue
+		}
+		if test.body != "" && rec.Body.String() != test.body {
+			t.Errorf("test #%d: expecting body %q, got %q", i+1, test.body, rec.Body.String())
+		}
+		for name, value := range test.header {
+			if got := rec.Header().Get(name); got != value {
+				t.Errorf("test #%d: expecting header %s %q, got %q", i+1, name, value, got)
+			}
+		}
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), []byte("body {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(dir, "style.css"), modTime, modTime)
+
+	for _, opts := range []static.Options{{Dir: dir}, {FS: files}} {
+		a := newApp(t, opts)
+		path := "/style.css"
+		if opts.FS != nil {
+			path = "/app.js"
+		}
+
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("expecting status 200 with ETag for %s, got %d %q", path, rec.Code, etag)
+		}
+		if opts.Dir != "" && rec.Header().Get("Last-Modified") != modTime.Format(http.TimeFormat) {
+			t.Errorf("expecting Last-Modified %s, got %s", modTime.Format(http.TimeFormat), rec.Header().Get("Last-Modified"))
+		}
+
+		// The embedded files have no modification time
+		modifiedStatus := http.StatusOK
+		if opts.Dir != "" {
+			modifiedStatus = http.StatusNotModified
+		}
+		tests := []struct {
+			header string
+			value  string
+			status int
+		}{
+			{"If-None-Match", etag, http.StatusNotModified},
+			{"If-None-Match", `"other"`, http.StatusOK},
+			{"If-Modified-Since", modTime.Format(http.TimeFormat), modifiedStatus},
+			{"Range", "bytes=0-3", http.StatusPartialContent},
+		}
+		for i, test := range tests {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set(test.header, test.value)
+			a.ServeHTTP(rec, req)
+			if rec.Code != test.status {
+				t.Errorf("test #%d: expecting status %d for %s %s, got %d", i+1, test.status, test.header, test.value, rec.Code)
+			}
+		}
+	}
+
+	if err := static.NewModule(static.Options{}).Bootstrap(app.NewApp("test")); err != static.ErrNoFiles {
+		t.Errorf("expecting error %v, got %v", static.ErrNoFiles, err)
+	}
+}
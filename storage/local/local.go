@@ -0,0 +1,157 @@
+// Package local implements a storage Backend on the local file system
+package local
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/syb-devs/goth/storage"
+)
+
+// Backend stores the blobs as files under a root directory, with the key as their relative path.
+// The content type of the blobs is derived from the extension of their key.
+type Backend struct {
+	root string
+}
+
+var _ storage.Backend = (*Backend)(nil)
+
+// New returns a Backend storing the blobs under the given directory, which is created if needed
+func New(root string) (*Backend, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &Backend{root: root}, nil
+}
+
+// Put stores the blob in the file for the key, writing it to a temporary file first, so the
+// readers never see a partial blob
+func (b *Backend) Put(ctx context.Context, key string, r io.Reader, contentType string) (storage.Info, error) {
+	name, err := b.path(key)
+	if err != nil {
+		return storage.Info{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return storage.Info{}, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return storage.Info{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, readerWithContext{ctx, r})
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return storage.Info{}, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return storage.Info{}, err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return storage.Info{}, err
+	}
+	return b.Stat(ctx, key)
+}
+
+// Open opens the file of the blob. The returned Object implements io.Seeker.
+func (b *Backend) Open(ctx context.Context, key string) (storage.Object, error) {
+	name, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	fi, err := f.Stat()
+	if err == nil && fi.IsDir() {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &object{File: f, info: info(key, fi)}, nil
+}
+
+// Stat returns the Info of the blob
+func (b *Backend) Stat(ctx context.Context, key string) (storage.Info, error) {
+	name, err := b.path(key)
+	if err != nil {
+		return storage.Info{}, err
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return storage.Info{}, notFound(err)
+	}
+	if fi.IsDir() {
+		return storage.Info{}, storage.ErrNotFound
+	}
+	return info(key, fi), nil
+}
+
+// Delete removes the file of the blob
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	name, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file path for the key, which must be valid
+func (b *Backend) path(key string) (string, error) {
+	if !storage.ValidKey(key) {
+		return "", storage.ErrInvalidKey
+	}
+	return filepath.Join(b.root, filepath.FromSlash(key)), nil
+}
+
+func info(key string, fi fs.FileInfo) storage.Info {
+	ctype := mime.TypeByExtension(path.Ext(key))
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	return storage.Info{Key: key, Size: fi.Size(), ContentType: ctype, ModTime: fi.ModTime()}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+type object struct {
+	*os.File
+	info storage.Info
+}
+
+func (o *object) Info() storage.Info {
+	return o.info
+}
+
+// readerWithContext stops reading once the context is done
+type readerWithContext struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r readerWithContext) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
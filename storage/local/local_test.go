@@ -0,0 +1,80 @@
+package local_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/syb-devs/goth/storage"
+	"github.com/syb-devs/goth/storage/local"
+)
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	b, err := local.New(t.TempDir() + "/blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := b.Put(ctx, "avatars/1/me.png", strings.NewReader("png data"), "image/png")
+	if err != nil {
+		t.Fatalf("error putting blob: %v", err)
+	}
+	if info.Key != "avatars/1/me.png" || info.Size != 8 || info.ContentType != "image/png" || info.ModTime.IsZero() {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if _, err := b.Put(ctx, "avatars/1/me.png", strings.NewReader("new png data"), "image/png"); err != nil {
+		t.Fatalf("error replacing blob: %v", err)
+	}
+
+	obj, err := b.Open(ctx, "avatars/1/me.png")
+	if err != nil {
+		t.Fatalf("error opening blob: %v", err)
+	}
+	data, _ := io.ReadAll(obj)
+	obj.Close()
+	if string(data) != "new png data" || obj.Info().Size != 12 {
+		t.Errorf("expecting the replaced blob, got %q (%+v)", data, obj.Info())
+	}
+	if _, ok := obj.(io.Seeker); !ok {
+		t.Error("expecting a seekable object")
+	}
+
+	if err := b.Delete(ctx, "avatars/1/me.png"); err != nil {
+		t.Fatalf("error deleting blob: %v", err)
+	}
+	if err := b.Delete(ctx, "avatars/1/me.png"); err != nil {
+		t.Errorf("expecting no error deleting a missing blob, got %v", err)
+	}
+
+	tests := []struct {
+		key string
+		err error
+	}{
+		{"avatars/1/me.png", storage.ErrNotFound},
+		{"avatars/1", storage.ErrNotFound},
+		{"../outside", storage.ErrInvalidKey},
+		{"/absolute", storage.ErrInvalidKey},
+		{"avatars/.hidden", storage.ErrInvalidKey},
+		{"avatars//double", storage.ErrInvalidKey},
+		{"", storage.ErrInvalidKey},
+	}
+	for i, test := range tests {
+		if _, err := b.Open(ctx, test.key); err != test.err {
+			t.Errorf("test #%d: expecting error %v opening %q, got %v", i+1, test.err, test.key, err)
+		}
+		if _, err := b.Stat(ctx, test.key); err != test.err {
+			t.Errorf("test #%d: expecting error %v for the info of %q, got %v", i+1, test.err, test.key, err)
+		}
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := b.Put(cctx, "cancelled.txt", strings.NewReader("data"), ""); err != context.Canceled {
+		t.Errorf("expecting error %v, got %v", context.Canceled, err)
+	}
+	if _, err := b.Stat(ctx, "cancelled.txt"); err != storage.ErrNotFound {
+		t.Errorf("expecting no blob after a cancelled put, got %v", err)
+	}
+}
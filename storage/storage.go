@@ -0,0 +1,242 @@
+// Package storage defines the Backend interface for saving and serving blobs (i.e. uploaded files),
+// and the FileRef type to reference them from the database Resources.
+//
+// The uploads of a request are saved with Save, and the returned FileRef stored in a field of the
+// Resource; the blobs are served back by the Handler, mounted at a path prefix:
+//
+//	type User struct {
+//		mongodb.Resource `bson:",inline"`
+//		Avatar *storage.FileRef `bson:"avatar,omitempty" json:"avatar,omitempty"`
+//	}
+//
+//	files, err := ctx.Files(app.FileLimits{AllowedTypes: []string{"image/*"}})
+//	...
+//	user.Avatar, err = storage.Save(ctx.Context(), backend, "avatars", files.First("avatar"))
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+)
+
+var (
+	// ErrNotFound is returned by the Backends when there is no blob with the given key
+	ErrNotFound = errors.New("storage: blob not found")
+	// ErrInvalidKey is returned by the Backends for malformed keys
+	ErrInvalidKey = errors.New("storage: invalid key")
+	// ErrNoFile is returned by Save when there is no uploaded file to save
+	ErrNoFile = errors.New("storage: no file to save")
+)
+
+// Info describes a stored blob
+type Info struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Object is a stored blob opened for reading. The Backends return objects implementing
+// io.Seeker when possible, so they can be served with range and conditional requests.
+type Object interface {
+	io.ReadCloser
+	Info() Info
+}
+
+// Backend stores blobs by key. The keys are slash separated paths, valid as per fs.ValidPath.
+type Backend interface {
+	// Put stores the blob read from r with the given key, replacing any existing one
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (Info, error)
+	// Open opens the blob with the given key, or returns ErrNotFound
+	Open(ctx context.Context, key string) (Object, error)
+	// Stat returns the Info of the blob with the given key, or ErrNotFound
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete deletes the blob with the given key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey checks if a key is valid for the Backends: a clean, relative, slash separated path
+// without empty, dot or hidden segments
+func ValidKey(key string) bool {
+	if !fs.ValidPath(key) || key == "." {
+		return false
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// FileRef references a blob stored in a Backend from a Resource field
+type FileRef struct {
+	Key         string `bson:"key" json:"key"`
+	Filename    string `bson:"filename,omitempty" json:"filename,omitempty"`
+	ContentType string `bson:"contentType,omitempty" json:"contentType,omitempty"`
+	Size        int64  `bson:"size" json:"size"`
+}
+
+// NewKey returns a new random key under the given prefix, keeping the extension of the filename
+func NewKey(prefix, filename string) string {
+	return newKey(prefix, strings.ToLower(path.Ext(path.Base(filename))))
+}
+
+func newKey(prefix, ext string) string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return path.Join(prefix, hex.EncodeToString(b[:])+ext)
+}
+
+// preferredExts are the extensions used for the common content types, as the ones registered in
+// the system may list others first (i.e. .asc for text/plain)
+var preferredExts = map[string]string{
+	"text/plain": ".txt",
+	"text/html":  ".html",
+	"text/xml":   ".xml",
+	"image/jpeg": ".jpg",
+	"audio/mpeg": ".mp3",
+}
+
+// extension returns the key extension for a content type: the one of the filename if it maps
+// to the same type, or else the preferred or first one registered for the type
+func extension(contentType, filename string) string {
+	ext := strings.ToLower(path.Ext(path.Base(filename)))
+	if mediaType, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext)); ext != "" && mediaType == contentType {
+		return ext
+	}
+	if ext, ok := preferredExts[contentType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// Save stores the uploaded file in the Backend, with a new key under the given prefix,
+// returning its reference. The key extension matches the content type detected for the file,
+// so the Backends serve it with that type, whatever the extension of the client filename.
+func Save(ctx context.Context, b Backend, prefix string, f *app.File) (*FileRef, error) {
+	if f == nil {
+		return nil, ErrNoFile
+	}
+	src, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	info, err := b.Put(ctx, newKey(prefix, extension(f.ContentType, f.Filename)), src, f.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRef{Key: info.Key, Filename: f.Filename, ContentType: f.ContentType, Size: info.Size}, nil
+}
+
+// Refs returns the FileRefs held by the fields of a Resource, including the embedded structs
+// and the slices of references
+func Refs(res interface{}) []*FileRef {
+	var refs []*FileRef
+	collectRefs(reflect.ValueOf(res), &refs)
+	return refs
+}
+
+var fileRefType = reflect.TypeOf(FileRef{})
+
+func collectRefs(v reflect.Value, refs *[]*FileRef) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == fileRefType:
+		if v.CanAddr() {
+			*refs = append(*refs, v.Addr().Interface().(*FileRef))
+		} else {
+			ref := v.Interface().(FileRef)
+			*refs = append(*refs, &ref)
+		}
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.PkgPath == "" || f.Anonymous {
+				collectRefs(v.Field(i), refs)
+			}
+		}
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		if t := v.Type().Elem(); t == fileRefType || t.Kind() == reflect.Ptr && t.Elem() == fileRefType {
+			for i := 0; i < v.Len(); i++ {
+				collectRefs(v.Index(i), refs)
+			}
+		}
+	}
+}
+
+// DeleteRefs deletes from the Backend the blobs referenced by a Resource, i.e. once it is deleted
+func DeleteRefs(ctx context.Context, b Backend, res interface{}) error {
+	for _, ref := range Refs(res) {
+		if ref.Key == "" {
+			continue
+		}
+		if err := b.Delete(ctx, ref.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns a Handler serving the blobs of the Backend, whose key is the MountParam URL
+// parameter, so it can be registered at a path prefix:
+//
+//	a.Handle("GET", "/files/*path", storage.Handler(backend))
+func Handler(b Backend) app.Handler {
+	return app.HandlerFunc(func(ctx *app.Context) error {
+		return Serve(ctx, b, ctx.URLParams.ByName(app.MountParam))
+	})
+}
+
+// Serve writes the blob with the given key to the response, answering conditional and range
+// requests if the Backend objects are seekable. Missing blobs are returned as app.ErrNotFound.
+func Serve(ctx *app.Context, b Backend, key string) error {
+	obj, err := b.Open(ctx.Context(), key)
+	if err == ErrNotFound || err == ErrInvalidKey {
+		return app.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	info := obj.Info()
+	h := ctx.Header()
+	if info.ContentType != "" {
+		h.Set("Content-Type", info.ContentType)
+	}
+	// The stored blobs could be HTML uploaded by the users, do not let them run in the App origin
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "sandbox")
+	if rs, ok := obj.(io.ReadSeeker); ok {
+		http.ServeContent(ctx.ResponseWriter, ctx.Request, path.Base(key), info.ModTime, rs)
+		return nil
+	}
+	if !info.ModTime.IsZero() {
+		h.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	_, err = io.Copy(ctx.ResponseWriter, obj)
+	return err
+}
@@ -0,0 +1,209 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+	"github.com/syb-devs/goth/log"
+	"github.com/syb-devs/goth/storage"
+	"github.com/syb-devs/goth/storage/local"
+)
+
+type album struct {
+	Cover  *storage.FileRef
+	Photos []storage.FileRef
+	Extra  []*storage.FileRef
+	Owner  struct{ Avatar storage.FileRef }
+	Title  string
+	hidden *storage.FileRef
+}
+
+func TestSaveAndServe(t *testing.T) {
+	backend, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	a.SetMuxer(stdmux.New(a.NewContextHTTP))
+	a.Handle("GET", "/files/*"+app.MountParam, storage.Handler(backend))
+
+	var ref *storage.FileRef
+	a.Handle("POST", "/albums", app.HandlerFunc(func(ctx *app.Context) error {
+		files, err := ctx.Files()
+		if err != nil {
+			return err
+		}
+		if ref, err = storage.Save(ctx.Context(), backend, "covers", files.First("cover")); err != nil {
+			return err
+		}
+		return ctx.EncodeStatus(http.StatusCreated, ref)
+	}))
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, _ := w.CreateFormFile("cover", "Beach.TXT")
+	fw.Write([]byte("a day at the beach"))
+	w.Close()
+	req := httptest.NewRequest("POST", "/albums", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || ref == nil {
+		t.Fatalf("expecting the cover to be saved, got status %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.HasPrefix(ref.Key, "covers/") || !strings.HasSuffix(ref.Key, ".txt") || ref.Filename != "Beach.TXT" ||
+		ref.ContentType != "text/plain" || ref.Size != 18 {
+		t.Errorf("unexpected file reference %+v", ref)
+	}
+
+	tests := []struct {
+		path   string
+		header string
+		value  string
+		status int
+		body   string
+	}{
+		{"/files/" + ref.Key, "", "", http.StatusOK, "a day at the beach"},
+		{"/files/" + ref.Key, "Range", "bytes=2-4", http.StatusPartialContent, "day"},
+		{"/files/covers/missing.txt", "", "", http.StatusNotFound, ""},
+		{"/files/covers/.hidden", "", "", http.StatusNotFound, ""},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest("GET", test.path, nil)
+		if test.header != "" {
+			req.Header.Set(test.header, test.value)
+		}
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("test #%d: expecting status %d, got %d", i+1, test.status, rec.Code)
+		}
+		if test.body != "" && rec.Body.String() != test.body {
+			t.Errorf("test #%d: expecting body %q, got %q", i+1, test.body, rec.Body.String())
+		}
+		if test.status == http.StatusOK && rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Errorf("test #%d: expecting the nosniff header", i+1)
+		}
+	}
+
+	if _, err := storage.Save(context.Background(), backend, "covers", nil); err != storage.ErrNoFile {
+		t.Errorf("expecting error %v, got %v", storage.ErrNoFile, err)
+	}
+}
+
+func TestSaveDetectedType(t *testing.T) {
+	backend, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	a.SetMuxer(stdmux.New(a.NewContextHTTP))
+	a.Handle("GET", "/files/*"+app.MountParam, storage.Handler(backend))
+	a.Handle("POST", "/uploads", app.HandlerFunc(func(ctx *app.Context) error {
+		files, err := ctx.Files()
+		if err != nil {
+			return err
+		}
+		ref, err := storage.Save(ctx.Context(), backend, "uploads", files.First("file"))
+		if err != nil {
+			return err
+		}
+		return ctx.EncodeStatus(http.StatusCreated, ref)
+	}))
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	tests := []struct {
+		filename    string
+		content     string
+		ext         string
+		contentType string
+	}{
+		{"x.html", png, ".png", "image/png"},
+		{"photo.PNG", png, ".png", "image/png"},
+		{"notes", "some notes", ".txt", "text/plain; charset=utf-8"},
+		{"page.txt", "<html><body>hi</body></html>", ".html", "text/html; charset=utf-8"},
+	}
+	for i, test := range tests {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		fw, _ := w.CreateFormFile("file", test.filename)
+		fw.Write([]byte(test.content))
+		w.Close()
+		req := httptest.NewRequest("POST", "/uploads", &body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		rec := httptest.NewRecorder()
+		a.ServeHTTP(rec, req)
+		var ref storage.FileRef
+		if err := json.Unmarshal(rec.Body.Bytes(), &ref); rec.Code != http.StatusCreated || err != nil {
+			t.Errorf("test #%d: expecting the file to be saved, got status %d: %s", i+1, rec.Code, rec.Body)
+			continue
+		}
+		if !strings.HasSuffix(ref.Key, test.ext) {
+			t.Errorf("test #%d: expecting a key with extension %s, got %s", i+1, test.ext, ref.Key)
+		}
+
+		rec = httptest.NewRecorder()
+		a.ServeHTTP(rec, httptest.NewRequest("GET", "/files/"+ref.Key, nil))
+		if ctype := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || ctype != test.contentType {
+			t.Errorf("test #%d: expecting the file served as %s, got status %d and %s", i+1, test.contentType, rec.Code, ctype)
+		}
+	}
+}
+
+func TestRefs(t *testing.T) {
+	backend, err := local.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, key := range []string{"cover.png", "1.png", "2.png", "3.png", "avatar.png", "other.png"} {
+		if _, err := backend.Put(ctx, key, strings.NewReader("png"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	al := &album{
+		Cover:  &storage.FileRef{Key: "cover.png"},
+		Photos: []storage.FileRef{{Key: "1.png"}, {Key: "2.png"}},
+		Extra:  []*storage.FileRef{nil, {Key: "3.png"}},
+		hidden: &storage.FileRef{Key: "other.png"},
+	}
+	al.Owner.Avatar.Key = "avatar.png"
+
+	var keys []string
+	for _, ref := range storage.Refs(al) {
+		keys = append(keys, ref.Key)
+	}
+	expected := []string{"cover.png", "1.png", "2.png", "3.png", "avatar.png"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expecting references %v, got %v", expected, keys)
+	}
+	// The references of addressable values can be updated
+	storage.Refs(al)[1].Filename = "first.png"
+	if al.Photos[0].Filename != "first.png" {
+		t.Error("expecting the reference to point to the resource field")
+	}
+
+	if err := storage.DeleteRefs(ctx, backend, al); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range expected {
+		if _, err := backend.Stat(ctx, key); err != storage.ErrNotFound {
+			t.Errorf("expecting %s to be deleted, got %v", key, err)
+		}
+	}
+	if _, err := backend.Stat(ctx, "other.png"); err != nil {
+		t.Errorf("expecting the blob not referenced by exported fields to be kept, got %v", err)
+	}
+}
@@ -2,18 +2,24 @@ package user
 
 import (
 	"errors"
+	"net/http"
 
 	"github.com/syb-devs/goth/app"
 	"github.com/syb-devs/goth/auth/jwt"
 	"github.com/syb-devs/goth/database"
+	_ "github.com/syb-devs/goth/validate/required"
 )
 
 var (
 	// ErrEmptyUserPass happens when no username and/or password is given for a user
+	//
+	// Deprecated: login returns a *validate.ValidationError listing the missing fields instead
 	ErrEmptyUserPass = errors.New("username and/or password not set")
 
-	// ErrInvalidUserPass happens when no  valid username and/or password is given for a user
-	ErrInvalidUserPass = errors.New("username and/or password is not valid")
+	// ErrInvalidUserPass happens when no valid username and/or password is given for a user.
+	// Unknown users and wrong passwords get the same 401 Unauthorized response, so the
+	// registered usernames can not be guessed.
+	ErrInvalidUserPass = &app.HTTPError{Status: http.StatusUnauthorized, Code: "invalid_credentials", Message: "username and/or password is not valid"}
 )
 
 type retJWT struct {
@@ -21,6 +27,9 @@ type retJWT struct {
 }
 
 func register(ctx *app.Context) error {
+	if _, _, err := ctx.Encoder(); err != nil {
+		return err
+	}
 	user := newUser(ctx)
 	err := ctx.Decode(user)
 	if err != nil {
@@ -28,7 +37,7 @@ func register(ctx *app.Context) error {
 	}
 	user.SetPassword(user.GetPassword())
 
-	if err = ctx.App.DB.Insert(user); err != nil {
+	if err = ctx.DB.Insert(ctx.Context(), user); err != nil {
 		return err
 	}
 	return ctx.Encode(user)
@@ -36,19 +45,19 @@ func register(ctx *app.Context) error {
 
 func login(ctx *app.Context) error {
 	loginData := &struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username string `json:"username" form:"username" validate:"required"`
+		Password string `json:"password" form:"password" validate:"required"`
 	}{}
-	err := ctx.Decode(loginData)
+	err := ctx.Bind(loginData)
 	if err != nil {
 		return err
 	}
-	if loginData.Username == "" || len(loginData.Password) == 0 {
-		return ErrEmptyUserPass
-	}
 	user := newUser(ctx).(Interface)
-	err = ctx.App.DB.FindOne(user, database.NewQ(
+	err = ctx.DB.FindOne(ctx.Context(), user, database.NewQ(
 		database.Dict{usernameDBField: loginData.Username}))
+	if errors.Is(err, database.ErrNotFound) {
+		return ErrInvalidUserPass
+	}
 	if err != nil {
 		return err
 	}
@@ -4,21 +4,35 @@ import (
 	"github.com/syb-devs/goth/app"
 )
 
-func init() {
-	app.RegisterModule(&module{BaseModule: app.NewBaseModule("goth.user")})
+// ModuleName is the name of the user module
+const ModuleName = "goth.user"
+
+// NewModule returns the user module, which registers the user HTTP routes
+// at the root path, using the pub chain. Add it to an App with App.Use.
+func NewModule() app.Module {
+	return &module{BaseModule: app.NewBaseModule(ModuleName)}
+}
+
+// NewModuleAt returns the user module, which registers the user HTTP routes
+// in the given route group
+func NewModuleAt(g *app.Group) app.Module {
+	return &module{BaseModule: app.NewBaseModule(ModuleName), group: g}
 }
 
 type module struct {
 	*app.BaseModule
+	group *app.Group
 }
 
 // Bootstrap performs initialization tasks, such as registering resources and HTTP routes
-func (m *module) Bootstrap(a *app.App, level int) error {
-	if level != 5 {
-		return nil
+func (m *module) Bootstrap(a *app.App) error {
+	g := m.group
+	if g == nil {
+		g = a.Group("", "pub")
 	}
-	a.Handle("POST", "/users/register", a.WrapHandlerFunc(register, "pub"))
-	a.Handle("POST", "/users/sessions", a.WrapHandlerFunc(login, "pub"))
+	users := g.Group("/users")
+	users.HandleFunc("POST", "/register", register).Named("users.register")
+	users.HandleFunc("POST", "/sessions", login).Named("users.sessions")
 
 	return nil
 }
@@ -69,6 +69,28 @@ func (r *Result) OK() bool {
 	return r.LogicError == nil && len(r.FieldErrors) == 0
 }
 
+// Err returns the logic error if any happened, or a *ValidationError if the data
+// did not pass the validation. It returns nil when the data is valid.
+func (r *Result) Err() error {
+	if r.LogicError != nil {
+		return r.LogicError
+	}
+	if len(r.FieldErrors) > 0 {
+		return &ValidationError{FieldErrors: r.FieldErrors}
+	}
+	return nil
+}
+
+// ValidationError is returned when the validated data does not conform to the rules
+type ValidationError struct {
+	FieldErrors
+}
+
+// Error returns a literal representation of the field errors
+func (e *ValidationError) Error() string {
+	return "validation failed: " + strings.TrimSpace(e.FieldErrors.String())
+}
+
 // Validator extracts and checks validation rules from struct tags
 // TODO(zareone) create a Rule cache?? map[reflect.Type]ruleParams
 type Validator struct {
@@ -114,7 +136,11 @@ func (v *Validator) Validate(data interface{}) Result {
 		if !fieldIsExported(field) {
 			continue
 		}
-		rules := parseRulesTag(field.Name, field.Tag.Get(v.tagName), v.ruleSeparator)
+		tag := field.Tag.Get(v.tagName)
+		if tag == "" {
+			continue
+		}
+		rules := parseRulesTag(field.Name, tag, v.ruleSeparator)
 
 		// fieldValue := sv.Field(curField).Interface()
 		fieldErrs, err := v.checkRules(sv.Interface(), rules)
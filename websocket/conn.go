@@ -0,0 +1,354 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, which are the opcodes of the frames (RFC 6455, section 5.2)
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes (RFC 6455, section 7.4.1)
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const (
+	// DefaultMaxMessageSize is the default maximum size in bytes of the messages read from a connection
+	DefaultMaxMessageSize = 1 << 20
+	// writeTimeout is the time a write is given to complete before the connection is considered broken
+	writeTimeout = 10 * time.Second
+	// maxControlPayload is the maximum payload size of control frames
+	maxControlPayload = 125
+)
+
+var (
+	// ErrClosed is returned when writing to a connection after sending the close frame
+	ErrClosed = errors.New("websocket: connection closed")
+	// ErrInvalidMessageType is returned when writing a message with an unknown type
+	ErrInvalidMessageType = errors.New("websocket: invalid message type")
+)
+
+// CloseError is returned by ReadMessage when the peer closes the connection
+type CloseError struct {
+	Code int
+	Text string
+}
+
+// Error returns the string representation of the error
+func (e *CloseError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("websocket: connection closed (%d)", e.Code)
+	}
+	return fmt.Sprintf("websocket: connection closed (%d): %s", e.Code, e.Text)
+}
+
+// IsCloseError checks if the error is a *CloseError with any of the given codes
+func IsCloseError(err error, codes ...int) bool {
+	var cerr *CloseError
+	if !errors.As(err, &cerr) {
+		return false
+	}
+	for _, code := range codes {
+		if cerr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// protocolError is a violation of the protocol by the peer, which closes the connection with its code
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return "websocket: " + e.msg
+}
+
+// Conn is a WebSocket connection. Its write methods are safe for concurrent use,
+// but only one goroutine should read from it.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	client      bool
+	subprotocol string
+
+	// MaxMessageSize is the maximum size in bytes of the messages read. Defaults to DefaultMaxMessageSize.
+	MaxMessageSize int64
+
+	idleTimeout time.Duration
+
+	wmu       sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, client bool, subprotocol string) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &Conn{conn: conn, br: br, client: client, subprotocol: subprotocol, MaxMessageSize: DefaultMaxMessageSize}
+}
+
+// Subprotocol returns the subprotocol negotiated in the handshake, if any
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// RemoteAddr returns the network address of the peer
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetIdleTimeout sets the maximum time to wait for the next frame of the peer before failing
+// the reads. Pings and pongs count as activity. Zero disables the timeout.
+func (c *Conn) SetIdleTimeout(d time.Duration) {
+	c.idleTimeout = d
+}
+
+// ReadMessage reads the next data message, reassembling its fragments. Pings are answered and
+// pongs discarded in the meantime. When the peer closes the connection, the close frame is
+// answered and a *CloseError returned; on protocol violations the connection is closed with the
+// matching close code. Either way, the connection must not be read from anymore.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+		switch opcode {
+		case PingMessage:
+			if err := c.WriteControl(PongMessage, payload); err != nil && err != ErrClosed {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			return 0, nil, c.closeReceived(payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "unexpected continuation frame"})
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "expecting a continuation frame"})
+			}
+			messageType = opcode
+		default:
+			return 0, nil, c.fail(&protocolError{CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode)})
+		}
+
+		if int64(len(data))+int64(len(payload)) > c.MaxMessageSize {
+			return 0, nil, c.fail(&protocolError{CloseMessageTooBig, "message too big"})
+		}
+		data = append(data, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(data) {
+				return 0, nil, c.fail(&protocolError{CloseInvalidPayload, "invalid UTF-8 in text message"})
+			}
+			return messageType, data, nil
+		}
+	}
+}
+
+// readFrame reads a frame, unmasking its payload
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	if c.idleTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.idleTimeout))
+	}
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		err = &protocolError{CloseProtocolError, "unexpected reserved bits"}
+		return
+	}
+	masked := header[1]&0x80 != 0
+	if masked == c.client {
+		err = &protocolError{CloseProtocolError, "invalid frame masking"}
+		return
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if opcode >= CloseMessage && (!fin || length > maxControlPayload) {
+		err = &protocolError{CloseProtocolError, "invalid control frame"}
+		return
+	}
+	if length < 0 || length > c.MaxMessageSize {
+		err = &protocolError{CloseMessageTooBig, "message too big"}
+		return
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, key[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		mask(key, payload)
+	}
+	return
+}
+
+// closeReceived answers a close frame of the peer and returns it as a *CloseError
+func (c *Conn) closeReceived(payload []byte) error {
+	cerr := &CloseError{Code: CloseNoStatus}
+	if len(payload) == 1 {
+		return c.fail(&protocolError{CloseProtocolError, "invalid close frame"})
+	}
+	if len(payload) >= 2 {
+		cerr.Code = int(binary.BigEndian.Uint16(payload))
+		cerr.Text = string(payload[2:])
+		if !utf8.ValidString(cerr.Text) {
+			return c.fail(&protocolError{CloseInvalidPayload, "invalid UTF-8 in close reason"})
+		}
+	}
+	code := cerr.Code
+	if code == CloseNoStatus {
+		code = CloseNormal
+	}
+	c.WriteClose(code, "")
+	return cerr
+}
+
+// fail closes the connection after a read error, sending the close code of the protocol violations
+func (c *Conn) fail(err error) error {
+	var perr *protocolError
+	if errors.As(err, &perr) {
+		c.WriteClose(perr.code, perr.msg)
+	}
+	return err
+}
+
+// WriteMessage sends a data message in a single frame
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return ErrInvalidMessageType
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// WriteControl sends a ping or pong frame, whose payload can not exceed 125 bytes
+func (c *Conn) WriteControl(messageType int, data []byte) error {
+	if messageType != PingMessage && messageType != PongMessage || len(data) > maxControlPayload {
+		return ErrInvalidMessageType
+	}
+	return c.writeFrame(messageType, data)
+}
+
+// WriteClose sends a close frame with the given code and reason. Nothing can be written afterwards.
+func (c *Conn) WriteClose(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+	return c.writeFrame(CloseMessage, payload)
+}
+
+func (c *Conn) writeFrame(opcode int, data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, 14+len(data))
+	frame = append(frame, 0x80|byte(opcode))
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(data); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126, byte(n>>8), byte(n))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		frame = append(frame, maskBit|127)
+		frame = append(frame, ext[:]...)
+	}
+	if c.client {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, data...)
+		mask(key, frame[start:])
+	} else {
+		frame = append(frame, data...)
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close sends a normal close frame, unless one was sent already, and closes the underlying connection
+func (c *Conn) Close() error {
+	c.WriteClose(CloseNormal, "")
+	return c.conn.Close()
+}
+
+// mask applies (and removes) the masking of a payload with the given key
+func mask(key [4]byte, data []byte) {
+	for i := range data {
+		data[i] ^= key[i%4]
+	}
+}
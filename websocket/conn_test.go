@@ -0,0 +1,119 @@
+package websocket
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// frame builds a masked client frame
+func frame(fin bool, opcode int, payload string) []byte {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	key := [4]byte{1, 2, 3, 4}
+	data := []byte(payload)
+	mask(key, data)
+	f := []byte{b0, 0x80 | byte(len(data))}
+	f = append(f, key[:]...)
+	return append(f, data...)
+}
+
+// pipe returns a server Conn and the raw client side of its connection
+func pipe(t *testing.T) (*Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close(); server.Close() })
+	client.SetDeadline(time.Now().Add(time.Second))
+	c := newConn(server, nil, false, "")
+	c.SetIdleTimeout(time.Second)
+	return c, client
+}
+
+func TestReadMessage(t *testing.T) {
+	c, client := pipe(t)
+	var raw bytes.Buffer
+	raw.Write(frame(false, TextMessage, "hello, "))
+	raw.Write(frame(true, PingMessage, "ping"))
+	raw.Write(frame(false, continuationFrame, "wor"))
+	raw.Write(frame(true, continuationFrame, "ld"))
+	raw.Write(frame(true, BinaryMessage, "\x00\x01"))
+	raw.Write(frame(true, CloseMessage, "\x03\xe9going"))
+	client.Write(raw.Bytes())
+
+	expected := []struct {
+		typ  int
+		data string
+	}{
+		{TextMessage, "hello, world"},
+		{BinaryMessage, "\x00\x01"},
+	}
+	for i, want := range expected {
+		typ, data, err := c.ReadMessage()
+		if err != nil || typ != want.typ || string(data) != want.data {
+			t.Errorf("message #%d: expecting %d %q, got %d %q (error %v)", i+1, want.typ, want.data, typ, data, err)
+		}
+	}
+	if _, _, err := c.ReadMessage(); !IsCloseError(err, CloseGoingAway) {
+		t.Errorf("expecting close error %d, got %v", CloseGoingAway, err)
+	}
+	if err := c.WriteMessage(TextMessage, []byte("late")); err != ErrClosed {
+		t.Errorf("expecting error %v writing after the close, got %v", ErrClosed, err)
+	}
+
+	// The ping was answered with a pong, and the close frame echoed
+	expectedRaw := []byte{0x80 | PongMessage, 4, 'p', 'i', 'n', 'g', 0x80 | CloseMessage, 2, 0x03, 0xe9}
+	got := make([]byte, len(expectedRaw))
+	if _, err := io.ReadFull(client, got); err != nil || !bytes.Equal(got, expectedRaw) {
+		t.Errorf("expecting frames %v, got %v (error %v)", expectedRaw, got, err)
+	}
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	unmasked := frame(true, TextMessage, "hi")
+	unmasked[1] &^= 0x80
+	unmasked = append(unmasked[:2], []byte("hi")...)
+
+	tests := []struct {
+		raw  []byte
+		code int
+	}{
+		{unmasked, CloseProtocolError},
+		{frame(true, continuationFrame, "orphan"), CloseProtocolError},
+		{append(frame(false, TextMessage, "a"), frame(true, TextMessage, "b")...), CloseProtocolError},
+		{frame(false, PingMessage, "fragmented"), CloseProtocolError},
+		{frame(true, TextMessage, "\xff\xfe"), CloseInvalidPayload},
+		{frame(true, BinaryMessage, "more than 8 bytes"), CloseMessageTooBig},
+		{append(frame(false, BinaryMessage, "12345"), frame(true, continuationFrame, "6789")...), CloseMessageTooBig},
+	}
+
+	for i, test := range tests {
+		c, client := pipe(t)
+		c.MaxMessageSize = 8
+		client.Write(test.raw)
+		if _, _, err := c.ReadMessage(); err == nil {
+			t.Errorf("test #%d: expecting an error", i+1)
+		}
+		var header [4]byte
+		if _, err := io.ReadFull(client, header[:]); err != nil {
+			t.Fatalf("test #%d: error reading the close frame: %v", i+1, err)
+		}
+		if code := int(header[2])<<8 | int(header[3]); header[0] != 0x80|CloseMessage || code != test.code {
+			t.Errorf("test #%d: expecting close code %d, got frame %v", i+1, test.code, header)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+// Package websocket implements WebSocket (RFC 6455) connections on top of the standard library,
+// and an app.Handler serving them, which routes the incoming messages to app.Handlers by type.
+//
+// The messages are decoded with the Codec of the upgrade request Context (JSON by default), and
+// their type is read from their TypeField:
+//
+//	{"type": "chat.send", "room": "lobby", "text": "hi"}
+//
+// Each message is served as a request of its own: the handler gets a Context whose request body
+// is the message, and whatever it encodes or writes to the response is sent back as a message.
+// The errors are sent back as problems by the App ErrorHandler. The Session of the connection is
+// available to the handlers through SessionFromContext, to push messages or join groups:
+//
+//	ws := websocket.New(websocket.Options{})
+//	ws.HandleFunc("chat.send", func(ctx *app.Context) error {
+//		var msg chatMessage
+//		if err := ctx.Bind(&msg); err != nil {
+//			return err
+//		}
+//		return ws.Broadcast(msg.Room, msg)
+//	})
+//	a.Handle("GET", "/ws", ws)
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/encoding"
+	"github.com/syb-devs/goth/kv"
+	"github.com/syb-devs/goth/log"
+)
+
+const (
+	// DefaultTypeField is the default message field holding the message type
+	DefaultTypeField = "type"
+	// DefaultPingInterval is the default interval of the pings sent to keep the connections alive
+	DefaultPingInterval = 30 * time.Second
+	// DefaultSendQueueSize is the default number of outgoing messages queued per Session
+	DefaultSendQueueSize = 64
+)
+
+var (
+	// ErrUnknownMessageType is sent back for the messages without a registered handler for their type
+	ErrUnknownMessageType = &app.HTTPError{Status: http.StatusNotFound, Code: "unknown_message_type", Message: "unknown message type"}
+	// ErrInvalidMessage is sent back for the messages which can not be decoded, or have no type
+	ErrInvalidMessage = &app.HTTPError{Status: http.StatusBadRequest, Code: "invalid_message", Message: "malformed message"}
+	// ErrSessionClosed is returned when sending messages through a closed Session
+	ErrSessionClosed = errors.New("websocket: session closed")
+)
+
+// Options are used to setup a websocket Handler
+type Options struct {
+	// TypeField is the message field holding the message type. Defaults to DefaultTypeField.
+	TypeField string
+	// Subprotocols are the subprotocols supported, in order of preference
+	Subprotocols []string
+	// CheckOrigin checks if the Origin of the upgrade request is allowed. Defaults to allow the
+	// requests without Origin header, and the ones whose Origin host matches the request Host.
+	CheckOrigin func(r *http.Request) bool
+	// MaxMessageSize is the maximum size in bytes of the incoming messages. Defaults to DefaultMaxMessageSize.
+	MaxMessageSize int64
+	// PingInterval is the interval of the pings sent to the clients. The connections without any
+	// activity for twice the interval are closed. Defaults to DefaultPingInterval; a negative
+	// value disables them.
+	PingInterval time.Duration
+	// Binary sends the messages in binary frames instead of text ones, for non textual codecs
+	Binary bool
+	// SendQueueSize is the number of outgoing messages queued per Session, waiting to be written.
+	// The Sessions falling behind, whose queue is full, are closed. Defaults to DefaultSendQueueSize.
+	SendQueueSize int
+	// OnConnect, if set, is called when a Session starts, before reading any message.
+	// Returning an error closes the connection with a policy violation.
+	OnConnect func(s *Session) error
+	// OnClose, if set, is called when a Session ends
+	OnClose func(s *Session)
+}
+
+// Handler upgrades the requests to WebSocket connections and serves their messages.
+// It implements app.Handler, so it can be registered as any other handler of the App.
+// Its Hub holds the groups of Sessions used for broadcasting.
+type Handler struct {
+	*Hub
+	opts Options
+
+	mu     sync.RWMutex
+	routes map[string]app.Handler
+}
+
+// New allocates and returns a new websocket Handler
+func New(opts Options) *Handler {
+	if opts.TypeField == "" {
+		opts.TypeField = DefaultTypeField
+	}
+	if opts.MaxMessageSize == 0 {
+		opts.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if opts.PingInterval == 0 {
+		opts.PingInterval = DefaultPingInterval
+	}
+	if opts.SendQueueSize <= 0 {
+		opts.SendQueueSize = DefaultSendQueueSize
+	}
+	return &Handler{Hub: NewHub(), opts: opts, routes: make(map[string]app.Handler)}
+}
+
+// Handle registers the handler for the messages of the given type, wrapped with the middlewares
+func (h *Handler) Handle(messageType string, handler app.Handler, mws ...app.Middleware) {
+	for i := len(mws) - 1; i >= 0; i-- {
+		handler = mws[i](handler)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.routes[messageType]; ok {
+		panic("websocket: handler already registered for message type " + messageType)
+	}
+	h.routes[messageType] = handler
+}
+
+// HandleFunc registers the handler function for the messages of the given type
+func (h *Handler) HandleFunc(messageType string, f app.HandlerFunc, mws ...app.Middleware) {
+	h.Handle(messageType, f, mws...)
+}
+
+// Serve upgrades the request to a WebSocket connection and serves its messages until it is closed.
+// The handshake failures are returned as HTTP errors. Once upgraded, the database connection of
+// the request Context is released, as every message gets a Context of its own.
+func (h *Handler) Serve(ctx *app.Context) error {
+	codec, err := ctx.Decoder()
+	if err != nil {
+		return err
+	}
+	conn, err := Upgrade(ctx, ctx.Request, UpgradeOptions{Subprotocols: h.opts.Subprotocols, CheckOrigin: h.opts.CheckOrigin})
+	if err != nil {
+		var herr *HandshakeError
+		if errors.As(err, &herr) {
+			return &app.HTTPError{Status: herr.Status, Code: "websocket_handshake", Message: herr.Message, Err: err}
+		}
+		return err
+	}
+	conn.MaxMessageSize = h.opts.MaxMessageSize
+	if ctx.Conn != nil {
+		ctx.Conn.Close()
+		ctx.Conn = nil
+		ctx.DB = ctx.App.DB.Repository
+	}
+
+	s := &Session{
+		conn:    conn,
+		handler: h,
+		ctx:     ctx,
+		codec:   codec,
+		msgType: TextMessage,
+		queue:   make(chan outgoing, h.opts.SendQueueSize),
+		groups:  make(map[string]struct{}),
+		done:    make(chan struct{}),
+	}
+	if h.opts.Binary {
+		s.msgType = BinaryMessage
+	}
+	s.serve()
+	return nil
+}
+
+// route returns the handler registered for the message type
+func (h *Handler) route(messageType string) (app.Handler, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	handler, ok := h.routes[messageType]
+	return handler, ok
+}
+
+type sessionKey struct{}
+
+// SessionFromContext returns the Session of the message served by the Context, if any
+func SessionFromContext(ctx *app.Context) (*Session, bool) {
+	s, ok := ctx.Context().Value(sessionKey{}).(*Session)
+	return s, ok
+}
+
+// Session is a WebSocket connection served by a Handler. Its methods are safe for concurrent use.
+type Session struct {
+	conn    *Conn
+	handler *Handler
+	ctx     *app.Context
+	codec   encoding.Codec
+	msgType int
+	queue   chan outgoing
+
+	mu     sync.Mutex
+	groups map[string]struct{}
+	closed bool
+	done   chan struct{}
+}
+
+// Subprotocol returns the subprotocol negotiated in the handshake, if any
+func (s *Session) Subprotocol() string {
+	return s.conn.Subprotocol()
+}
+
+// Request returns the upgrade request of the Session
+func (s *Session) Request() *http.Request {
+	return s.ctx.Request
+}
+
+// User returns the user of the upgrade request, if any
+func (s *Session) User() app.User {
+	return s.ctx.User
+}
+
+// Store returns the Store of the Session, shared by the Contexts of all its messages
+func (s *Session) Store() *kv.Store {
+	return s.ctx.Store
+}
+
+// URLParams returns the URL parameters of the upgrade request
+func (s *Session) URLParams() app.URLParams {
+	return s.ctx.URLParams
+}
+
+// Done returns a channel that is closed when the Session ends
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Send encodes the value with the Session codec and sends it as a message
+func (s *Session) Send(v interface{}) error {
+	data, err := s.encode(v)
+	if err != nil {
+		return err
+	}
+	return s.write(data)
+}
+
+func (s *Session) encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.codec.Encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Join adds the Session to the group, so it gets the messages broadcast to it
+func (s *Session) Join(group string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.groups[group] = struct{}{}
+	s.handler.Hub.join(group, s)
+}
+
+// Leave removes the Session from the group
+func (s *Session) Leave(group string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.groups, group)
+	s.handler.Hub.leave(group, s)
+}
+
+// Close closes the connection with the given code and reason, once the queued messages are
+// written, ending the Session
+func (s *Session) Close(code int, reason string) error {
+	return s.enqueue(outgoing{close: true, code: code, reason: reason})
+}
+
+// outgoing is a message queued to be written, or the close frame ending the Session
+type outgoing struct {
+	data   []byte
+	close  bool
+	code   int
+	reason string
+}
+
+// write queues the data to be sent as a message
+func (s *Session) write(data []byte) error {
+	return s.enqueue(outgoing{data: data})
+}
+
+// enqueue queues an outgoing message without blocking. If the queue is full, the Session is
+// falling behind and its connection is closed.
+func (s *Session) enqueue(m outgoing) error {
+	select {
+	case <-s.done:
+		return ErrSessionClosed
+	default:
+	}
+	select {
+	case s.queue <- m:
+		return nil
+	default:
+		log.Debugf("websocket session closed: send queue full")
+		s.conn.conn.Close()
+		return ErrSessionClosed
+	}
+}
+
+// writeQueue writes the queued messages until the Session ends. Closing the connection on
+// failure makes the reads fail too, ending the Session.
+func (s *Session) writeQueue() {
+	for {
+		select {
+		case m := <-s.queue:
+			if m.close {
+				s.conn.WriteClose(m.code, m.reason)
+				s.conn.conn.Close()
+				return
+			}
+			if err := s.conn.WriteMessage(s.msgType, m.data); err != nil {
+				s.conn.conn.Close()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// serve runs the Session: it reads and dispatches the messages until the connection is closed
+func (s *Session) serve() {
+	defer s.end()
+	go s.writeQueue()
+	if interval := s.handler.opts.PingInterval; interval > 0 {
+		s.conn.SetIdleTimeout(2 * interval)
+		go s.ping(interval)
+	}
+	if s.handler.opts.OnConnect != nil {
+		if err := s.handler.opts.OnConnect(s); err != nil {
+			log.Debugf("websocket session rejected: %v", err)
+			s.conn.WriteClose(ClosePolicyViolation, "")
+			return
+		}
+	}
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if !IsCloseError(err, CloseNormal, CloseGoingAway, CloseNoStatus) {
+				log.Debugf("websocket session ended: %v", err)
+			}
+			return
+		}
+		s.dispatch(data)
+	}
+}
+
+// dispatch serves a message with the handler registered for its type, in a Context of its own
+func (s *Session) dispatch(data []byte) {
+	req := s.ctx.Request.WithContext(context.WithValue(s.ctx.Context(), sessionKey{}, s))
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+
+	ctx := s.ctx.App.NewContextHTTP(&messageWriter{s: s, header: make(http.Header)}, req)
+	ctx.Codec = s.codec
+	ctx.User = s.ctx.User
+	ctx.Store = s.ctx.Store
+	ctx.URLParams = s.ctx.URLParams
+
+	var h app.Handler = app.HandlerFunc(func(*app.Context) error { return ErrInvalidMessage })
+	var msg map[string]interface{}
+	if err := s.codec.Decode(bytes.NewReader(data), &msg); err == nil {
+		if messageType, ok := msg[s.handler.opts.TypeField].(string); ok && messageType != "" {
+			if route, ok := s.handler.route(messageType); ok {
+				h = route
+			} else {
+				h = app.HandlerFunc(func(*app.Context) error { return ErrUnknownMessageType })
+			}
+		}
+	}
+	app.Dispatch(ctx, h)
+}
+
+// ping sends pings every interval, until the Session ends
+func (s *Session) ping(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.conn.WriteControl(PingMessage, nil); err != nil {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// end leaves all the groups and closes the connection
+func (s *Session) end() {
+	s.mu.Lock()
+	s.closed = true
+	for group := range s.groups {
+		s.handler.Hub.leave(group, s)
+	}
+	s.groups = nil
+	close(s.done)
+	s.mu.Unlock()
+
+	s.conn.Close()
+	if s.handler.opts.OnClose != nil {
+		s.handler.opts.OnClose(s)
+	}
+}
+
+// messageWriter is the ResponseWriter of the message Contexts, which sends each write as a message
+type messageWriter struct {
+	s      *Session
+	header http.Header
+}
+
+func (w *messageWriter) Header() http.Header { return w.header }
+func (w *messageWriter) WriteHeader(int)     {}
+
+func (w *messageWriter) Write(data []byte) (int, error) {
+	if err := w.s.write(bytes.TrimSuffix(data, []byte("\n"))); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
@@ -0,0 +1,214 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// acceptGUID is the GUID appended to the handshake key to compute the accept one (RFC 6455, section 1.3)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError is returned when the opening handshake fails
+type HandshakeError struct {
+	// Status is the HTTP status the server should respond with
+	Status  int
+	Message string
+}
+
+// Error returns the string representation of the error
+func (e *HandshakeError) Error() string {
+	return "websocket: " + e.Message
+}
+
+// UpgradeOptions are used to customise the server side of the opening handshake
+type UpgradeOptions struct {
+	// Subprotocols are the subprotocols supported by the server, in order of preference
+	Subprotocols []string
+	// CheckOrigin checks if the Origin of the request is allowed. Defaults to allow the requests
+	// without Origin header, and the ones whose Origin host matches the request Host.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Upgrade performs the server side of the opening handshake, taking over the connection of the
+// request. The ResponseWriter must implement http.Hijacker. If the request is not a valid
+// handshake, nothing is written to the response and a *HandshakeError is returned.
+func Upgrade(w http.ResponseWriter, r *http.Request, opts UpgradeOptions) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, &HandshakeError{http.StatusMethodNotAllowed, "handshake method must be GET"}
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, &HandshakeError{http.StatusBadRequest, "not a websocket handshake"}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, &HandshakeError{http.StatusUpgradeRequired, "unsupported websocket version"}
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, &HandshakeError{http.StatusBadRequest, "invalid Sec-WebSocket-Key"}
+	}
+	checkOrigin := opts.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, &HandshakeError{http.StatusForbidden, "origin not allowed"}
+	}
+
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, &HandshakeError{http.StatusInternalServerError, "response writer does not implement http.Hijacker"}
+	}
+	subprotocol := selectSubprotocol(r, opts.Subprotocols)
+	netConn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// Clear the deadlines the http.Server may have set (i.e. ReadTimeout), which do not apply
+	// to the WebSocket connection
+	netConn.SetDeadline(time.Time{})
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return newConn(netConn, brw.Reader, false, subprotocol), nil
+}
+
+// Dial opens a client connection to the WebSocket server at the given ws:// or wss:// URL.
+// The headers, if any, are sent with the handshake request (i.e. Origin, Authorization or
+// Sec-WebSocket-Protocol). The handshake response is returned along with the connection,
+// and on failure when the server responded.
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	var secure bool
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+		secure = true
+	default:
+		return nil, nil, fmt.Errorf("websocket: unsupported URL scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		if secure {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	var d net.Dialer
+	netConn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secure {
+		tlsConn := tls.Client(netConn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, nil, err
+		}
+		netConn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req := &http.Request{Method: http.MethodGet, URL: u, Host: u.Host, Header: make(http.Header)}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(netConn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContains(res.Header, "Upgrade", "websocket") ||
+		res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		netConn.Close()
+		return nil, res, &HandshakeError{res.StatusCode, "handshake rejected by the server: " + res.Status}
+	}
+	netConn.SetDeadline(time.Time{})
+	return newConn(netConn, br, true, res.Header.Get("Sec-WebSocket-Protocol")), res, nil
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value for a handshake key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains checks if a comma separated header contains the given token, case insensitively
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectSubprotocol picks the first of the supported subprotocols requested by the client
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, s := range supported {
+		if headerContains(r.Header, "Sec-WebSocket-Protocol", s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// sameOrigin allows the requests without Origin, and the ones whose Origin host matches the Host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
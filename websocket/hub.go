@@ -0,0 +1,77 @@
+package websocket
+
+import "sync"
+
+// Hub holds groups of Sessions, to broadcast messages to them. It is safe for concurrent use.
+type Hub struct {
+	mu     sync.RWMutex
+	groups map[string]map[*Session]struct{}
+}
+
+// NewHub allocates and returns a new Hub
+func NewHub() *Hub {
+	return &Hub{groups: make(map[string]map[*Session]struct{})}
+}
+
+// Broadcast sends the value to all the Sessions in the group, encoded with the codec of each one.
+// It does not wait for the messages to be written: they are queued in each Session, and the ones
+// falling behind are closed. The Sessions whose connection is broken are skipped, as they leave
+// the group when they end. It returns the first encoding error, if any.
+func (h *Hub) Broadcast(group string, v interface{}) error {
+	var first error
+	for _, s := range h.Members(group) {
+		data, err := s.encode(v)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		s.write(data)
+	}
+	return first
+}
+
+// Members returns the Sessions in the group
+func (h *Hub) Members(group string) []*Session {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	members := make([]*Session, 0, len(h.groups[group]))
+	for s := range h.groups[group] {
+		members = append(members, s)
+	}
+	return members
+}
+
+// Groups returns the names of the groups with any Session
+func (h *Hub) Groups() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	groups := make([]string, 0, len(h.groups))
+	for group := range h.groups {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func (h *Hub) join(group string, s *Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	members, ok := h.groups[group]
+	if !ok {
+		members = make(map[*Session]struct{})
+		h.groups[group] = members
+	}
+	members[s] = struct{}{}
+}
+
+func (h *Hub) leave(group string, s *Session) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if members, ok := h.groups[group]; ok {
+		delete(members, s)
+		if len(members) == 0 {
+			delete(h.groups, group)
+		}
+	}
+}
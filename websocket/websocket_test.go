@@ -0,0 +1,260 @@
+package websocket_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/syb-devs/goth/app"
+	"github.com/syb-devs/goth/app/middleware/buffer"
+	"github.com/syb-devs/goth/app/middleware/chain"
+	"github.com/syb-devs/goth/app/mux/stdmux"
+	"github.com/syb-devs/goth/database"
+	"github.com/syb-devs/goth/log"
+	_ "github.com/syb-devs/goth/validate/required"
+	"github.com/syb-devs/goth/websocket"
+)
+
+type chatMessage struct {
+	Type string `json:"type"`
+	Room string `json:"room" param:"room"`
+	Text string `json:"text" validate:"required"`
+}
+
+func newServer(t *testing.T, opts websocket.Options) (*websocket.Handler, string) {
+	ws := websocket.New(opts)
+	ws.HandleFunc("echo", func(ctx *app.Context) error {
+		var msg chatMessage
+		if err := ctx.Bind(&msg); err != nil {
+			return err
+		}
+		msg.Type = "echoed"
+		return ctx.Encode(msg)
+	})
+	ws.HandleFunc("say", func(ctx *app.Context) error {
+		var msg chatMessage
+		if err := ctx.Bind(&msg); err != nil {
+			return err
+		}
+		msg.Type = "said"
+		return ws.Broadcast(msg.Room, msg)
+	})
+
+	a := app.NewApp("test")
+	a.Log = log.NilLogger{}
+	a.DB.Connection = &countingConn{}
+	a.SetMuxer(stdmux.New(a.NewContextHTTP))
+	a.AddChain(chain.New(buffer.New()), "main")
+	a.Group("", "main").Handle("GET", "/rooms/:room", ws)
+	srv := httptest.NewUnstartedServer(a)
+	// The deadlines of the server do not apply to the upgraded connections
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return ws, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// openConns counts the open copies of the countingConn database connections
+var openConns int32
+
+// countingConn is a database connection counting its open copies
+type countingConn struct{}
+
+func (c *countingConn) Connect(database.ConnectionParams) error { return nil }
+func (c *countingConn) Interface() interface{}                  { return nil }
+func (c *countingConn) Close() error                            { atomic.AddInt32(&openConns, -1); return nil }
+func (c *countingConn) Copy() database.Connection {
+	atomic.AddInt32(&openConns, 1)
+	return &countingConn{}
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("error dialing %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetIdleTimeout(time.Second)
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg string) {
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatalf("error sending %s: %v", msg, err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	typ, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("error reading message: %v", err)
+	}
+	if typ != websocket.TextMessage {
+		t.Errorf("expecting a text message, got type %d", typ)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("error decoding message %s: %v", data, err)
+	}
+	return msg
+}
+
+func TestRouting(t *testing.T) {
+	_, url := newServer(t, websocket.Options{})
+	conn := dial(t, url+"/rooms/lobby")
+
+	tests := []struct {
+		message  string
+		field    string
+		expected interface{}
+	}{
+		{`{"type": "echo", "text": "hi"}`, "type", "echoed"},
+		{`{"type": "echo", "text": "hi"}`, "room", "lobby"},
+		{`{"type": "echo", "text": ""}`, "code", "validation_failed"},
+		{`{"type": "shout", "text": "hi"}`, "code", "unknown_message_type"},
+		{`{"text": "hi"}`, "code", "invalid_message"},
+		{`not json`, "code", "invalid_message"},
+		{`{"type": "echo", "text": "still there"}`, "text", "still there"},
+	}
+
+	for i, test := range tests {
+		send(t, conn, test.message)
+		if got := receive(t, conn)[test.field]; got != test.expected {
+			t.Errorf("test #%d: expecting %s %v, got %v", i+1, test.field, test.expected, got)
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	joined := make(chan struct{}, 3)
+	ws, url := newServer(t, websocket.Options{
+		OnConnect: func(s *websocket.Session) error {
+			s.Join(s.URLParams().ByName("room"))
+			joined <- struct{}{}
+			return nil
+		},
+	})
+	alice, bob, carol := dial(t, url+"/rooms/a"), dial(t, url+"/rooms/a"), dial(t, url+"/rooms/b")
+	for i := 0; i < 3; i++ {
+		<-joined
+	}
+	if n := len(ws.Members("a")); n != 2 {
+		t.Errorf("expecting 2 members in group a, got %d", n)
+	}
+
+	send(t, alice, `{"type": "say", "text": "hello a"}`)
+	send(t, carol, `{"type": "say", "text": "hello b"}`)
+	for _, c := range []*websocket.Conn{alice, bob} {
+		if msg := receive(t, c); msg["text"] != "hello a" || msg["type"] != "said" {
+			t.Errorf("expecting the broadcast to group a, got %v", msg)
+		}
+	}
+	if msg := receive(t, carol); msg["text"] != "hello b" {
+		t.Errorf("expecting the broadcast to group b, got %v", msg)
+	}
+
+	// Closed sessions leave their groups
+	bob.Close()
+	deadline := time.Now().Add(time.Second)
+	for len(ws.Members("a")) != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := len(ws.Members("a")); n != 1 {
+		t.Errorf("expecting 1 member in group a after closing a connection, got %d", n)
+	}
+}
+
+func TestLongLivedSession(t *testing.T) {
+	connected := make(chan int32, 1)
+	_, url := newServer(t, websocket.Options{
+		PingInterval: -1,
+		OnConnect: func(s *websocket.Session) error {
+			connected <- atomic.LoadInt32(&openConns)
+			return nil
+		},
+	})
+	conn := dial(t, url+"/rooms/lobby")
+	if open := <-connected; open != 0 {
+		t.Errorf("expecting the database connection of the upgrade request to be released, got %d open", open)
+	}
+
+	// Past the server ReadTimeout, without pings
+	time.Sleep(200 * time.Millisecond)
+	send(t, conn, `{"type": "echo", "text": "still there"}`)
+	if msg := receive(t, conn); msg["text"] != "still there" {
+		t.Errorf("expecting the echo after the server ReadTimeout, got %v", msg)
+	}
+}
+
+func TestSlowSession(t *testing.T) {
+	joined := make(chan struct{}, 1)
+	ws, url := newServer(t, websocket.Options{
+		SendQueueSize: 4,
+		OnConnect: func(s *websocket.Session) error {
+			s.Join("slow")
+			joined <- struct{}{}
+			return nil
+		},
+	})
+	// The client never reads, so the writes block once the socket buffers are full
+	dial(t, url+"/rooms/lobby")
+	<-joined
+
+	payload := strings.Repeat("x", 1<<20)
+	start := time.Now()
+	for i := 0; i < 64; i++ {
+		if err := ws.Broadcast("slow", payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expecting the broadcasts not to wait for the slow session, took %v", elapsed)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(ws.Members("slow")) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := len(ws.Members("slow")); n != 0 {
+		t.Errorf("expecting the slow session to be closed, got %d members", n)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	_, url := newServer(t, websocket.Options{
+		OnConnect: func(s *websocket.Session) error {
+			if s.URLParams().ByName("room") == "private" {
+				return errors.New("private room")
+			}
+			return nil
+		},
+	})
+	httpURL := "http" + strings.TrimPrefix(url, "ws")
+
+	res, err := http.Get(httpURL + "/rooms/lobby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expecting status %d for a plain request, got %d", http.StatusBadRequest, res.StatusCode)
+	}
+
+	ctx := context.Background()
+	_, res, err = websocket.Dial(ctx, url+"/rooms/lobby", http.Header{"Origin": {"https://evil.example.com"}})
+	if err == nil || res == nil || res.StatusCode != http.StatusForbidden {
+		t.Errorf("expecting the cross origin handshake to be forbidden, got %v", err)
+	}
+
+	conn := dial(t, url+"/rooms/private")
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("expecting the connection to be closed with a policy violation, got %v", err)
+	}
+}